@@ -0,0 +1,181 @@
+package komi
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// BatchErrors can be returned by batched work (see `WorkBatch`) to fail only
+// some jobs of the batch, the error at each index is the error of the job at
+// the same index, nil if the job succeeded.
+type BatchErrors []error
+
+// Error returns how many jobs of the batch failed.
+func (e BatchErrors) Error() string {
+	return fmt.Sprintf("%d of %d jobs in the batch failed", len(e.Unwrap()), len(e))
+}
+
+// Unwrap returns the non-nil errors, so `errors.Is` can be used.
+func (e BatchErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// SubmitBatch sends the jobs to the pool for processing in order, see `Submit`.
+// It stops at the first job that couldn't be submitted, returning its error.
+func (p Pool[I, _]) SubmitBatch(jobs []I) error {
+	for i, job := range jobs {
+		if err := p.Submit(job); err != nil {
+			return fmt.Errorf("submitting job %d of the batch: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// laborBatch will perform batches of tasks, starting with the one that has
+// just been picked up from the queue. Returns the number of tasks performed
+// and true if work panicked.
+func (p *Pool[I, _]) laborBatch(t task[I]) (int, bool) {
+	performed, panicked := 0, false
+	for next := &t; next != nil; {
+		// If the pool is ordered, don't run too far ahead of the
+		// oldest job, whose outcome hasn't been released yet.
+		if p.sequencer != nil {
+			p.sequencer.admit(next.seq)
+		}
+		var batch []task[I]
+		batch, next = p.collectBatch(*next)
+
+		p.laborersBusy.Add(1)
+		if p.performBatch(batch) {
+			panicked = true
+		}
+		p.laborersBusy.Add(-1)
+		performed += len(batch)
+		if p.scheduler != nil {
+			for _, t := range batch {
+				p.scheduler.finished(t)
+			}
+		}
+	}
+	return performed, panicked
+}
+
+// collectBatch will gather the queued tasks following the first one into a batch,
+// until it's full or the linger time passes. If the pool is ordered, a task outside
+// of the reorder window ends the batch and is returned to start the next one,
+// as it can only be performed after the ones in this batch are released.
+func (p *Pool[I, _]) collectBatch(first task[I]) ([]task[I], *task[I]) {
+	batch := []task[I]{first}
+
+	// Without linger, only take the tasks that are already waiting.
+	var linger <-chan time.Time
+	if p.settings.BatchLinger > 0 {
+		timer := time.NewTimer(p.settings.BatchLinger)
+		defer timer.Stop()
+		linger = timer.C
+	}
+	for len(batch) < p.settings.BatchSize {
+		var t task[I]
+		if linger == nil {
+			select {
+			case t = <-p.ready:
+			default:
+				return batch, nil
+			}
+		} else {
+			select {
+			case t = <-p.ready:
+			case <-linger:
+				return batch, nil
+			case <-p.laborersStopSignal:
+				return batch, nil
+			case <-p.ctx.Done():
+				return batch, nil
+			}
+		}
+		p.dequeued()
+		p.recordQueueWait(t)
+		if p.sequencer != nil && !p.sequencer.admits(t.seq) {
+			return batch, &t
+		}
+		batch = append(batch, t)
+	}
+	return batch, nil
+}
+
+// performBatch will run batched work on the tasks and send their outputs and
+// errors to their channels. Returns true if work panicked.
+func (p *Pool[I, O]) performBatch(batch []task[I]) bool {
+	jobs := make([]I, len(batch))
+	for i, t := range batch {
+		jobs[i] = t.job
+	}
+	started := time.Now()
+	outs, errs := p.callBatch(jobs)
+	executed := time.Since(started)
+
+	panicked := false
+	for i, t := range batch {
+		p.executionTimes.observe(executed)
+		if errs[i] != nil {
+			poolErr := newPoolError(t.job, errs[i], 1, nil)
+			panicked = panicked || poolErr.Panic != nil
+			p.release(t, func() { p.failed(t, poolErr) })
+			continue
+		}
+		p.release(t, func() { p.succeeded(t, outs[i]) })
+	}
+	return panicked
+}
+
+// callBatch will run batched work on the jobs, returning the outputs and the errors
+// of the jobs, converting a panic in work into an error wrapping `ErrJobPanicked`.
+func (p *Pool[I, O]) callBatch(jobs []I) (outs []O, errs []error) {
+	// Respect the rate limit before every batch.
+	if err := p.throttle(p.ctx); err != nil {
+		return nil, batchOutcomes(len(jobs), 0, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err := &panicError{
+				value: r,
+				stack: debug.Stack(),
+			}
+			outs, errs = nil, batchOutcomes(len(jobs), 0, err)
+		}
+	}()
+	outs, err := p.workBatch(jobs)
+	return outs, batchOutcomes(len(jobs), len(outs), err)
+}
+
+// batchOutcomes maps the error returned by batched work to the errors of
+// the jobs, failing all of them, unless it's `BatchErrors` for every job.
+// Jobs without errors fail too, if work didn't return an output for every job.
+func batchOutcomes(jobs, outs int, err error) []error {
+	errs := make([]error, jobs)
+	var batchErrs BatchErrors
+	switch {
+	case errors.As(err, &batchErrs) && len(batchErrs) == jobs:
+		copy(errs, batchErrs)
+	case err != nil:
+		for i := range errs {
+			errs[i] = err
+		}
+	}
+	if outs != jobs {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = fmt.Errorf("batch work returned %d outputs for %d jobs", outs, jobs)
+			}
+		}
+	}
+	return errs
+}
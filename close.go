@@ -1,53 +1,78 @@
 package komi
 
+import "fmt"
+
 // signalForChildren will have a signal sent when this pool
 // is getting closed. Use this for children to know when the
 // parent is leaving.
-func (p Pool[_, _]) signalForChildren() <-chan Signal {
+func (p *Pool[_, _]) signalForChildren() <-chan Signal {
 	return p.tellChildrenToClose
 }
 
 // IsClosed returns true if the pool is closed, false otherwise.
 func (p Pool[_, _]) IsClosed() bool {
-	return p.closed
+	return p.State() == StateClosed
 }
 
-// anotherPoolIsSendingJobsHere return true if another pool is feeding
+// anotherPoolIsSendingJobsHere return true if other pools are feeding
 // jobs into this pool, false otherwise.
 func (p Pool[_, _]) anotherPoolIsSendingJobsHere() bool {
-	return p.childsClosureSignal != nil
+	closureSignals, _ := p.children.connected()
+	return len(closureSignals) > 0
 }
 
 // Close will issue a pool closure request and takes a bool value, if true,
 // any pending jobs will be ignored and forcefully closed. Note that the user
 // can request a pool closure if and only if it is not connected to another
 // pool. In that case, the parent pool will have to issue the closure request.
-func (p *Pool[_, _]) Close(force ...bool) {
-	p.closureInternalWait.Add(1)
-	p.closureRequest <- (len(force) > 0 && force[0])
-	p.closureInternalWait.Wait()
+// Returns `ErrPoolClosed` if the pool is already closing or closed.
+func (p *Pool[I, _]) Close(force ...bool) error {
+	if p.State() >= StateClosing {
+		return ErrPoolClosed
+	}
+	request := closure[I]{forced: len(force) > 0 && force[0], done: make(chan error, 1)}
+	select {
+	case p.closureRequest <- request:
+	case <-p.closedSignal:
+		// The closure subroutine has left, as the pool got closed meanwhile.
+		return ErrPoolClosed
+	}
+	return <-request.done
 }
 
-func (p *Pool[_, _]) closureRequestListener() {
+func (p *Pool[I, _]) closureRequestListener() {
 waiting:
 	// Block until a request comes in
-	forced := <-p.closureRequest
-	p.log.Debug("Got a request to close", "forced", forced, "parent_requested", p.connectorRequestedClosure)
+	request := <-p.closureRequest
+	forced := request.forced
+	p.log.Debug("Got a request to close", "forced", forced, "parent_requested", request.parentRequested)
 	// Refuse to close if it had already been done.
-	if p.IsClosed() {
+	if p.State() != StateRunning {
 		p.log.Warn("Pool is already closed")
-		p.closureInternalWait.Done()
+		request.reply(ErrPoolClosed)
 		goto waiting
 	}
-	if p.IsConnected() && !p.connectorRequestedClosure {
-		p.log.Warn("Only the parent can close this pool", "parent", p.parent.Name())
-		p.closureInternalWait.Done()
+	if p.IsConnected() && !request.parentRequested {
+		p.log.Warn("Only the parent can close this pool", "parent", p.parentsNames())
+		request.reply(fmt.Errorf("only the parent %s can close this pool", p.parentsNames()))
 		goto waiting
 	}
 
-	if p.childsWait != nil && !forced {
-		p.log.Debug("Waiting for the child's Wait")
-		p.childsWait()
+	// A graceful shutdown has its own way of closing the pool.
+	if request.shutdown != nil {
+		p.shutdown(request)
+		return
+	}
+
+	// Stop taking jobs from anyone, but the children.
+	p.setState(StateDraining)
+
+	closureSignals, childrenWaits := p.children.connected()
+	if !forced {
+		p.log.Debug("Waiting for the children's Wait", "children", len(childrenWaits))
+		for _, childWait := range childrenWaits {
+			childWait()
+		}
 	}
 
 	// This is a flag that will force closure (override waiting).
@@ -57,15 +82,17 @@ waiting:
 	if p.anotherPoolIsSendingJobsHere() {
 		// Send the closed signal to any connected pools. We need to issue a closure
 		// request to the dependent (child) pools before locking ourselves (optionally)
-		// and waiting for those dependent (child) pools to leave.
-		p.log.Info("Sending a signal for the child to leave...")
-		p.tellChildrenToClose <- signal
-		<-p.childsClosureSignal
-		p.log.Info("Child left, resuming closure...")
+		// and waiting for those dependent (child) pools to leave. Closing the channel
+		// will broadcast the signal to all the children.
+		p.log.Info("Sending a signal for the children to leave...", "children", len(closureSignals))
 		close(p.tellChildrenToClose)
+		for _, closureSignal := range closureSignals {
+			<-closureSignal
+		}
+		p.log.Info("Children left, resuming closure...")
 
 		shouldForceNonetheless = true
-		drain(p.inputs)
+		p.discard(take(p.inputs))
 		drain(p.outputs)
 	}
 
@@ -74,6 +101,9 @@ waiting:
 		p.Wait()
 	}
 
+	// Stop taking jobs at all, and wait for the submitters to leave.
+	p.stopIntake()
+
 	// Start sending a signal for all laborers to quit.
 	p.stopLaborers()
 
@@ -86,24 +116,44 @@ waiting:
 		p.log.Debug("Connectors quit")
 	}
 
+	// If the pool is prioritized, discard the jobs left in the priority queue,
+	// once the scheduler stops taking them from inputs.
+	if p.scheduler != nil {
+		p.discard(p.takeScheduled())
+	}
+
+	// If jobs could spill over, discard the ones that never made it to inputs,
+	// once the feeder stops sending them there.
+	if p.overflow != nil {
+		p.discard(p.takeOverflow())
+	}
+
+	// Discard the jobs laborers picked up after being told to quit.
+	p.discard(p.leftovers.take())
+
 	// Close the inputs channel so no new work is processed.
-	drain(p.inputs)
+	p.discard(take(p.inputs))
 	close(p.inputs)
 
+	// If we have been sending dropped jobs, close the dead letters channel.
+	if p.deadLetters != nil {
+		close(p.deadLetters)
+	}
+
 	// If we have been writing outputs, close the channel.
-	if p.producesOutputs() {
+	if p.outputs != nil {
 		drain(p.outputs)
 		close(p.outputs)
 	}
 
 	// If we have been writing errors, close the channel.
-	if p.producesErrors() {
+	if p.errors != nil {
 		drain(p.errors)
 		close(p.errors)
 	}
 
-	// Mark the flag that the pool is closed.
-	p.closed = true
+	// Mark the pool as closed.
+	p.setState(StateClosed)
 
 	// I like Internet Historian.
 	p.log.Debug("Pool is closed", "completed", p.JobsCompleted())
@@ -111,7 +161,24 @@ waiting:
 	p.closedSignal <- signal
 	close(p.closedSignal)
 
-	if !p.connectorRequestedClosure {
-		p.closureInternalWait.Done()
+	// Let the connected (parent) pools continue their closure.
+	p.releaseParents()
+	p.cancel()
+
+	request.reply(nil)
+}
+
+// discard will record the tasks removed from the queue by the closure without
+// performing any work on them, resolving their futures.
+func (p *Pool[I, _]) discard(tasks []task[I]) {
+	for _, t := range tasks {
+		p.unqueued(t)
+	}
+}
+
+// reply will send the outcome of the request, if anyone waits for it.
+func (c closure[_]) reply(err error) {
+	if c.done != nil {
+		c.done <- err
 	}
 }
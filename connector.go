@@ -2,6 +2,7 @@ package komi
 
 import (
 	"errors"
+	"strings"
 	"sync"
 )
 
@@ -11,19 +12,24 @@ type PoolConnector[O any] interface {
 	// Submit will submit a job to the connected (parent) pool.
 	Submit(O) error
 
+	// submitFromChild will submit a job to the connected (parent) pool,
+	// which takes jobs from its children until they leave, even if draining.
+	submitFromChild(O) error
+
 	// signalForChildren will have a signal go through it when the
 	// connected (parent) pool is closing, therefore, letting know
 	// all the children pools that they should themselves close.
 	signalForChildren() <-chan Signal
 
 	// waitBeforeClosure will force the connected (parent) pool to
-	// wait for a signal from this channel before proceeding with
-	// a closure request.
+	// wait for a signal from this channel (in addition to the ones from
+	// other children) before proceeding with a closure request.
 	waitBeforeClosure(<-chan Signal)
 
 	// setChildsWait is useful for parents gracefully waiting for
-	// their children to wrap up work.
-	setChildsWait(func())
+	// their children to wrap up work, it adds to other children's,
+	// together with the function telling how many jobs the child has.
+	setChildsWait(func(), func() int64)
 
 	// IsClosed returns true if the connected (parent) pool is closed,
 	// false otherwise.
@@ -33,7 +39,33 @@ type PoolConnector[O any] interface {
 	Name() string
 }
 
+// Connect will send all the outputs of this pool as jobs to the connected (parent)
+// pool, making this pool its dependent (child). A pool with jobs waiting or outputs
+// unread can't be connected.
 func (p *Pool[I, O]) Connect(parent PoolConnector[O]) error {
+	return p.connect(nil, parent)
+}
+
+// Broadcast will send every output of this pool as a job to each of the
+// connected (parent) pools, making this pool a dependent (child) of all of them.
+func (p *Pool[I, O]) Broadcast(parents ...PoolConnector[O]) error {
+	return p.connect(nil, parents...)
+}
+
+// Route will send every output of this pool as a job to one of the connected
+// (parent) pools, the one at index `selector(output)` (modulo the number of parents),
+// making this pool a dependent (child) of all of them. If the chosen parent has
+// already left, the next one still connected is used.
+func (p *Pool[I, O]) Route(selector func(O) int, parents ...PoolConnector[O]) error {
+	if selector == nil {
+		return errors.New("can't route outputs with a nil selector")
+	}
+	return p.connect(selector, parents...)
+}
+
+// connect will connect this pool to the parents, if selector is nil, outputs
+// are broadcast to all of the parents, otherwise, they are routed by it.
+func (p *Pool[I, O]) connect(selector func(O) int, parents ...PoolConnector[O]) error {
 	// This should not trigger, because `noValue` is a package internal,
 	// so it shouldn't be accessible to the user to connect outputless
 	// pools to other pools. Consider this as a last defense line.
@@ -41,12 +73,39 @@ func (p *Pool[I, O]) Connect(parent PoolConnector[O]) error {
 		return errors.New("can't connect because not producing outputs")
 	}
 
+	// Outputs handed over to the output handler can't be sent anywhere else.
+	if p.outputHandler != nil {
+		return errors.New("can't connect because outputs go to the output handler")
+	}
+
+	// There should be someone to connect to.
+	if len(parents) < 1 {
+		return errors.New("can't connect to no parents")
+	}
+
+	// Keep the submitters out while connecting, as the outputs of jobs
+	// performed before and after connecting are counted differently.
+	if !p.intake.TryLock() {
+		return errors.New("can't connect while jobs are being submitted")
+	}
+	defer p.intake.Unlock()
+
+	// Only a running pool can be connected.
+	if err := p.stateErr(false); err != nil {
+		return err
+	}
+
 	// This pool is already sending its outputs to a connected (parent)
 	// pool, therefore, refuse this connection request.
 	if p.IsConnected() {
 		return errors.New("a connector is already running")
 	}
 
+	// The outputs of the jobs already submitted are meant for the outputs channel.
+	if p.JobsWaiting() > 0 || len(p.outputs) > 0 {
+		return errors.New("can't connect while the pool has jobs waiting or outputs unread")
+	}
+
 	// Create a wait group that will let us know if there are any
 	// running connectors in this pool.
 	p.connectorsActive = &sync.WaitGroup{}
@@ -55,24 +114,44 @@ func (p *Pool[I, O]) Connect(parent PoolConnector[O]) error {
 	// to quit their execution.
 	p.connectorsStopSignal = make(chan Signal, 1)
 
-	// Set the connected (parent) pool.
-	p.parent = parent
+	// Set the connected (parent) pools and how outputs are sent to them.
+	p.route = selector
+	p.connections = make([]*connection[O], len(parents))
+	for i, parent := range parents {
+		p.connections[i] = &connection[O]{
+			parent:        parent,
+			releaseSignal: make(chan Signal),
+		}
 
-	// Tell the connected (parent) pool to wait for this dependent (child)
-	// pool's closure before they can close themselves.
-	p.parent.waitBeforeClosure(p.closedSignal)
+		// Tell the connected (parent) pool to wait for this dependent (child)
+		// pool to release it before they can close themselves.
+		parent.waitBeforeClosure(p.connections[i].releaseSignal)
 
-	// Set child's wait.
-	p.parent.setChildsWait(p.Wait)
+		// Set child's wait.
+		parent.setChildsWait(p.Wait, p.JobsWaiting)
+	}
+	p.connected.Store(true)
+
+	// Parents that are closing will be reported here, it's buffered, so the
+	// watchers below never block if the connector has already quit.
+	parentsLeaving := make(chan *connection[O], len(parents))
+	for _, conn := range p.connections {
+		go func(conn *connection[O]) {
+			<-conn.parent.signalForChildren()
+			parentsLeaving <- conn
+		}(conn)
+	}
 
 	// Kick off the connector.
 	go func(p *Pool[I, O]) {
+		parentsRemaining := len(p.connections)
 		for {
 			select {
 			case result := <-p.outputs:
 				// If the pool produced a new output, grab it and send it
-				// as a new job to the connected pool.
-				parent.Submit(result)
+				// as a new job to the connected pool(s).
+				p.forward(result)
+				p.performedWork(true)
 				continue
 				// ---
 			case <-p.connectorsStopSignal:
@@ -80,19 +159,28 @@ func (p *Pool[I, O]) Connect(parent PoolConnector[O]) error {
 				// as done and kill the scope.
 				p.connectorsActive.Done()
 				return
-			case <-p.parent.signalForChildren():
+			case conn := <-parentsLeaving:
+				// Stop sending outputs to the leaving parent.
+				conn.left = true
+				parentsRemaining--
+
+				// If other parents are still around, let the leaving one continue
+				// its closure, while this pool keeps working for the others.
+				if parentsRemaining > 0 {
+					p.log.Debug("Released the leaving parent pool", "parent", conn.parent.Name())
+					conn.release()
+					continue
+				}
+
 				// If the target pool is closed, this pool should also get
 				// automatically closed, as no one would be continuing to
 				// consume this pool's outputs.
-				p.log.Debug("Closing because the parent pool is leaving...", "parent", p.parent.Name())
+				p.log.Debug("Closing because the parent pool is leaving...", "parent", conn.parent.Name())
 
-				// Mark this flag, so the closure subroutine doesn't hang until
-				// this connector responds back, because it is the one, which
-				// requested closure, and not the user.
-				p.connectorRequestedClosure = true
-
-				// Request the closure of this pool.
-				p.closureRequest <- false
+				// Request the closure of this pool, marked as requested by the
+				// parent, so the closure subroutine doesn't wait for this connector
+				// to respond back, as it is the one, which requested closure.
+				p.closureRequest <- closure[I]{parentRequested: true}
 				continue
 			}
 		}
@@ -101,22 +189,70 @@ func (p *Pool[I, O]) Connect(parent PoolConnector[O]) error {
 	// Mark this new connector as a running instance.
 	p.connectorsActive.Add(1)
 
-	// Log the connected (parent) pool.
-	p.log.Debug("Connected to the parent pool", "parent", p.parent.Name())
+	// Log the connected (parent) pools.
+	p.log.Debug("Connected to the parent pool", "parent", p.parentsNames())
 
 	return nil
 }
 
+// forward will send the output to the connected (parent) pools that
+// haven't left yet, either all of them or the routed one.
+func (p *Pool[I, O]) forward(result O) {
+	if p.route == nil {
+		for _, conn := range p.connections {
+			if !conn.left {
+				p.submitToParent(conn, result)
+			}
+		}
+		return
+	}
+	// Find the selected parent, or the next one, if it has left.
+	n := len(p.connections)
+	selected := ((p.route(result) % n) + n) % n
+	for i := 0; i < n; i++ {
+		if conn := p.connections[(selected+i)%n]; !conn.left {
+			p.submitToParent(conn, result)
+			return
+		}
+	}
+}
+
+// submitToParent will submit the output as a job to the connected (parent) pool.
+func (p *Pool[_, O]) submitToParent(conn *connection[O], result O) {
+	if err := conn.parent.submitFromChild(result); err != nil {
+		p.log.Warn("Failed to submit to the parent pool", "parent", conn.parent.Name(), "err", err)
+	}
+}
+
+// releaseParents will let all the connected (parent) pools, which are
+// still waiting on this pool, continue their closure.
+func (p *Pool[_, _]) releaseParents() {
+	for _, conn := range p.connections {
+		conn.release()
+	}
+}
+
+// parentsNames returns comma-separated names of the connected (parent) pools.
+func (p Pool[_, _]) parentsNames() string {
+	names := make([]string, len(p.connections))
+	for i, conn := range p.connections {
+		names[i] = conn.parent.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
 // IsConnected will return true if this pool already has an active connector.
-// This is equivalent to having a connected (parent) pool.
+// This is equivalent to having connected (parent) pools.
 func (p Pool[_, _]) IsConnected() bool {
-	return p.parent != nil
+	return p.connected.Load()
 }
 
 // waitBeforeClosure will force the pool to wait for a signal from this channel
-// before it can proceed with a closure request.
+// (and all the other children's) before it can proceed with a closure request.
 func (p *Pool[_, _]) waitBeforeClosure(waitForThis <-chan Signal) {
-	p.childsClosureSignal = waitForThis
+	p.children.lock.Lock()
+	defer p.children.lock.Unlock()
+	p.children.closureSignals = append(p.children.closureSignals, waitForThis)
 }
 
 // Name returns the name of the pool.
@@ -124,7 +260,52 @@ func (p *Pool[_, _]) Name() string {
 	return p.settings.Name
 }
 
-// setChildsWait sets the child's wait function.
-func (p *Pool[_, _]) setChildsWait(childWait func()) {
-	p.childsWait = childWait
+// setChildsWait adds the child's wait and jobs waiting functions.
+func (p *Pool[_, _]) setChildsWait(childWait func(), childJobsWaiting func() int64) {
+	p.children.lock.Lock()
+	defer p.children.lock.Unlock()
+	p.children.waits = append(p.children.waits, childWait)
+	p.children.jobsWaiting = append(p.children.jobsWaiting, childJobsWaiting)
+}
+
+// connected returns the closure signals and wait functions of all
+// dependent (child) pools connected to the pool.
+func (c *children) connected() ([]<-chan Signal, []func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closureSignals, c.waits
+}
+
+// waiting returns the number of jobs waiting in all the dependent (child) pools.
+func (c *children) waiting() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	waiting := int64(0)
+	for _, jobsWaiting := range c.jobsWaiting {
+		waiting += jobsWaiting()
+	}
+	return waiting
+}
+
+// connection is the connector's link to one of the connected (parent) pools.
+type connection[O any] struct {
+	// parent is the connected (parent) pool.
+	parent PoolConnector[O]
+
+	// releaseSignal is closed when this pool lets the parent continue its closure.
+	releaseSignal chan Signal
+
+	// released is true if the release signal has been sent.
+	released bool
+
+	// left is true if the parent is closing, so no outputs should be sent to it.
+	left bool
+}
+
+// release will let the parent continue its closure, if not already done.
+func (c *connection[_]) release() {
+	if !c.released {
+		c.released = true
+		close(c.releaseSignal)
+	}
 }
@@ -1,9 +1,12 @@
 package komi
 
 import (
+	"context"
+	"log/slog"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
@@ -17,6 +20,9 @@ const (
 
 	// defaultRatio sets the size to laborers ratio.
 	defaultRatio = 2
+
+	// defaultAging is how long a job waits for its priority to go up by one.
+	defaultAging = time.Second
 )
 
 var (
@@ -35,7 +41,11 @@ type Pool[I, O any] struct {
 	settings *Settings
 
 	// log is the pool's logger to be used.
-	log *log.Logger
+	log *slog.Logger
+
+	// defaultLog is the handler of the pool's logger, unless the user
+	// supplied their own logger or disabled logging.
+	defaultLog *log.Logger
 
 	// workSimple could be set by the user if the kind of work
 	// they want the pool to perform has no outputs or errors.
@@ -53,26 +63,52 @@ type Pool[I, O any] struct {
 	// work they want the pool to perform produces outputs and errors.
 	workRegularWithErrors func(I) (O, error)
 
+	// workCtx could be set by the user if the kind of work they want
+	// the pool to perform is context-aware and produces outputs and errors.
+	workCtx func(context.Context, I) (O, error)
+
+	// workBatch could be set by the user if the kind of work they want the pool
+	// to perform takes batches of jobs and produces outputs and errors.
+	workBatch func([]I) ([]O, error)
+
+	// workState could be set by the user if the kind of work they want the pool to
+	// perform needs its own state in every laborer, it initializes the state for the
+	// laborer with the ID, returning the work performer bound to the state and the
+	// state's teardown.
+	workState func(int) (func(context.Context, I) (O, error), func(), error)
+
 	// workPerformer is a function signature that will be set to
 	// whatever work that the user gave for the pool.
-	workPerformer func(I)
+	workPerformer func(context.Context, I) (O, error)
+
+	// ctx is the pool-level context, when it's done, laborers quit and
+	// pending submitters are released with its error.
+	ctx context.Context
 
-	// tellChildrenToClose is a channel where this pool will send a
-	// signal to tell all the dependent (child) pools (the ones that send
+	// cancel cancels the pool-level context, once the pool is closed.
+	cancel context.CancelFunc
+
+	// tellChildrenToClose is a channel this pool will close to broadcast a
+	// signal to all the dependent (child) pools (the ones that send
 	// their outputs to here) to start shutting down.
 	tellChildrenToClose chan Signal
 
-	// closed will be set to true when the pool is fully closed.
-	closed bool
-
-	// closedSignal is a channel that is set by dependent (child) channels,
-	// so they can tell their connected (parent) pools that they are closed.
-	// This is because the parent will close if and only if ALL their dependent
-	// (child) pools have closed.
+	// closedSignal is a channel that is set by the pool when it's closed.
+	// Connected (parent) pools are released separately through connections, as
+	// the parent will close if and only if ALL their dependent (child) pools
+	// have closed or released it.
 	closedSignal chan Signal
 
 	// inputs channel is where the jobs are coming from.
-	inputs chan I
+	inputs chan task[I]
+
+	// ready channel is where laborers pick up the jobs from, it's the inputs
+	// channel itself, unless the pool is prioritized.
+	ready chan task[I]
+
+	// scheduler feeds laborers the jobs with the highest priority first, nil
+	// unless the pool is prioritized.
+	scheduler *scheduler[I]
 
 	// outputs channel is where `workPerformer` will send jobs' outputs (if work
 	// is at least "Regular") to.
@@ -95,6 +131,38 @@ type Pool[I, O any] struct {
 	// jobsSucceeded tracks the number of completed jobs with non-nil results.
 	jobsSucceeded *atomic.Int64
 
+	// jobsRejected tracks the number of jobs rejected because the pool was full.
+	jobsRejected *atomic.Int64
+
+	// jobsRetried tracks the number of retries made by the retry policy.
+	jobsRetried *atomic.Int64
+
+	// jobsDropped tracks the number of jobs dropped or rejected by the overflow policy.
+	jobsDropped *atomic.Int64
+
+	// outputHandler could be set by the user to consume the outputs inline,
+	// instead of sending them to the outputs channel.
+	outputHandler func(O)
+
+	// outputHandlerLock serializes the output handler calls, unless
+	// concurrent calls are allowed in settings.
+	outputHandlerLock *sync.Mutex
+
+	// errorHandler could be set by the user to consume the pool errors inline,
+	// instead of sending them to the errors channel.
+	errorHandler func(PoolError[I])
+
+	// dropHandler could be set by the user to receive jobs dropped or rejected
+	// by the overflow policy.
+	dropHandler func(I)
+
+	// deadLetters channel is where jobs dropped or rejected by the overflow policy
+	// are sent to, if enabled in settings.
+	deadLetters chan I
+
+	// overflow holds the spilled jobs, if the overflow policy is `OverflowSpill`.
+	overflow *overflow[I]
+
 	// laborersStopSignal is a channel used by the pool to tell all laborers to quit,
 	// consumed by laborers.
 	laborersStopSignal chan Signal
@@ -103,6 +171,56 @@ type Pool[I, O any] struct {
 	// have gracefully quit.
 	laborersActive *sync.WaitGroup
 
+	// laborersRetireSignal is a channel used by the pool to tell one laborer to quit,
+	// when the number of laborers is reduced.
+	laborersRetireSignal chan Signal
+
+	// laborersLock guards changes to the number of laborers, so no laborers are
+	// spawned after the pool told them all to quit.
+	laborersLock *sync.Mutex
+
+	// leftovers are the jobs laborers picked up after they were told to quit.
+	leftovers *leftovers[I]
+
+	// discardOutcomes is set when the shutdown deadline passes, so the outputs
+	// and errors of the abandoned jobs are discarded.
+	discardOutcomes *atomic.Bool
+
+	// laborerIDs hands out the IDs of the laborers.
+	laborerIDs *laborerIDs
+
+	// laborersWanted is the number of laborers the pool should have.
+	laborersWanted *atomic.Int64
+
+	// laborersCount is the number of currently running laborers.
+	laborersCount *atomic.Int64
+
+	// laborersBusy is the number of laborers currently performing work.
+	laborersBusy *atomic.Int64
+
+	// queueWaitTotal is the total time jobs have spent waiting in the queue, in
+	// nanoseconds, used with `queueWaitCount` to tell the average latency.
+	queueWaitTotal *atomic.Int64
+
+	// queueWaitCount is the number of jobs that have been picked up from the queue.
+	queueWaitCount *atomic.Int64
+
+	// queueWaitTimes is the histogram of how long jobs waited in the queue.
+	queueWaitTimes *histogram
+
+	// executionTimes is the histogram of how long work was performed on jobs.
+	executionTimes *histogram
+
+	// created is when the pool was created.
+	created time.Time
+
+	// limiter enforces the rate limit on laborers starting jobs.
+	limiter *rateLimiter
+
+	// timeThrottled is the total time laborers have waited for the rate limit,
+	// in nanoseconds.
+	timeThrottled *atomic.Int64
+
 	// connectorsStopSignal is a channel used by the pool to tell all connectors to quit,
 	// consumed by connectors.
 	connectorsStopSignal chan Signal
@@ -111,34 +229,41 @@ type Pool[I, O any] struct {
 	// have gracefully quit.
 	connectorsActive *sync.WaitGroup
 
-	// connectorRequestedClosure is set to true if the closure request has been supplied
-	// by one of the connectors, this will happen if the connected (parent) pool has let this
-	// dependent (child) pool know that its closing, therefore the child should also shutdown.
-	connectorRequestedClosure bool
+	// connections are handles that the child can use to communicate with its parents.
+	connections []*connection[O]
+
+	// connected is set once the connections are, so laborers can tell whether
+	// the pool is connected without touching the connections.
+	connected *atomic.Bool
 
-	// childsClosureSignal is a back-channel that is used by the child to tell the parent that the
-	// child left, therefore continuing parent's active closure request.
-	childsClosureSignal <-chan Signal
+	// route selects the parent each output is sent to, outputs are sent to all
+	// parents if it's nil.
+	route func(O) int
 
-	// parent is a handle that the child can use to communicate with its parent.
-	parent PoolConnector[O]
+	// children keeps track of dependent (child) pools connected to this pool.
+	children *children
 
-	// childsWait is dependent (child) pool's waiting function.
-	childsWait func()
+	// noJobsWaitingSignal is broadcast when the number of waiting jobs
+	// drops to 0, so any number of `Wait` calls can return.
+	noJobsWaitingSignal *broadcaster
 
-	// currentlyWaitingForJobs is set to true when `Wait` is active.
-	currentlyWaitingForJobs *atomic.Bool
+	// closureRequest will have a request go through when someone wants to close the pool.
+	closureRequest chan closure[I]
 
-	// noJobsCurrentlyWaitingSignal fires when `Wait` is active and
-	// number of waiting jobs is 0.
-	noJobsCurrentlyWaitingSignal chan Signal
+	// state is the pool's lifecycle state, see `State`.
+	state *atomic.Int32
 
-	// closureRequest will have a signal go through when someone wants to close the pool,
-	// true value passed means it was forced
-	closureRequest chan bool
+	// intake is held for reading by submitters while they send jobs to the inputs
+	// channel, so the pool can wait for all of them to leave before closing it.
+	intake *sync.RWMutex
 
-	// closureInternalWait will block .Close() until closure handler leaves.
-	closureInternalWait *sync.WaitGroup
+	// intakeStopSignal is closed when the pool stops taking jobs, releasing the
+	// submitters blocked on the full inputs channel.
+	intakeStopSignal chan Signal
+
+	// sequencer releases jobs' outputs and errors in submission order, nil
+	// unless the pool is ordered.
+	sequencer *sequencer
 }
 
 // PoolError is produced by the pool when a work performed by the pool fails
@@ -149,4 +274,82 @@ type PoolError[I any] struct {
 
 	// Error is the error returned by pool's work performer.
 	Error error
+
+	// Panic is the value work panicked with, nil if it didn't panic.
+	Panic any
+
+	// Stack is the stack trace of the laborer at the moment of panic,
+	// nil if work didn't panic.
+	Stack []byte
+
+	// Attempts is the number of times work was performed on the job,
+	// which is more than one if the pool has a retry policy, and zero
+	// if the job was abandoned after its deadline.
+	Attempts int
+
+	// PreviousErrors are the errors returned by the attempts before
+	// the last one (see `Error`), oldest first.
+	PreviousErrors []error
+}
+
+// task is a job wrapped together with the context it was submitted with.
+type task[I any] struct {
+	// job is the job submitted by the user.
+	job I
+
+	// ctx is the context the job was submitted with, work performers
+	// will receive a context derived from it.
+	ctx context.Context
+
+	// seq is the job's sequence number, used when the pool is ordered.
+	seq uint64
+
+	// submitted is when the job was submitted.
+	submitted time.Time
+
+	// priority is the job's priority, used when the pool is prioritized.
+	priority int
+
+	// future is the job's `*Future[O]`, nil unless the job was submitted with
+	// `SubmitFuture`, the task doesn't know the output type.
+	future any
+}
+
+// closure is a request to close the pool.
+type closure[I any] struct {
+	// forced is true if any pending jobs should be ignored.
+	forced bool
+
+	// parentRequested is true if the closure request has been supplied by one of the
+	// connectors, this will happen if the connected (parent) pool has let this dependent
+	// (child) pool know that its closing, therefore the child should also shutdown.
+	parentRequested bool
+
+	// shutdown is the deadline of a graceful shutdown, nil unless the request
+	// has been supplied by `Shutdown`.
+	shutdown context.Context
+
+	// report is filled in by the graceful shutdown.
+	report *ShutdownReport[I]
+
+	// done receives the outcome of the request, nil if nobody waits for it.
+	done chan error
+}
+
+// children keeps track of any number of dependent (child) pools connected
+// to the same (parent) pool.
+type children struct {
+	// lock guards the closure signals and waits.
+	lock sync.Mutex
+
+	// closureSignals are back-channels that are used by the children to tell the
+	// parent that they left, therefore continuing parent's active closure request.
+	closureSignals []<-chan Signal
+
+	// waits are dependent (child) pools' waiting functions.
+	waits []func()
+
+	// jobsWaiting are dependent (child) pools' functions returning
+	// the number of their waiting jobs.
+	jobsWaiting []func() int64
 }
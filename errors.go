@@ -0,0 +1,53 @@
+package komi
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrJobPanicked is wrapped by the pool error's error when work panicked.
+	ErrJobPanicked = errors.New("job panicked")
+
+	// ErrPoolClosed is returned when the pool is closing or closed.
+	ErrPoolClosed = errors.New("pool is closed")
+
+	// ErrPoolDraining is returned when a job is submitted to the pool that
+	// is finishing its jobs before closing.
+	ErrPoolDraining = errors.New("pool is draining")
+
+	// ErrPoolFull is returned when a job is submitted to a full pool,
+	// which can't wait for the room to free up.
+	ErrPoolFull = errors.New("pool is full")
+
+	// ErrJobTimeout is wrapped by the pool error's error when the job
+	// didn't complete before its deadline.
+	ErrJobTimeout = errors.New("job timed out")
+
+	// ErrJobDiscarded is the error of the future of a job that was
+	// discarded, either dropped or left by the closure, before it was performed.
+	ErrJobDiscarded = errors.New("job was discarded")
+
+	// ErrLaborerInit is wrapped by the error of initializing a laborer's
+	// state, see `WorkWithState`.
+	ErrLaborerInit = errors.New("initializing laborer")
+)
+
+// panicError is the error made out of a recovered panic in work.
+type panicError struct {
+	// value is the value the work panicked with.
+	value any
+
+	// stack is the stack trace of the panicking laborer.
+	stack []byte
+}
+
+// Error returns the panic description.
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrJobPanicked, e.value)
+}
+
+// Unwrap returns `ErrJobPanicked`, so `errors.Is` can be used.
+func (e *panicError) Unwrap() error {
+	return ErrJobPanicked
+}
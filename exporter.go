@@ -0,0 +1,155 @@
+package komi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StatsReporter is anything that can report pool's stats, all pools satisfy it.
+type StatsReporter interface {
+	// Stats returns a snapshot of the pool's counters.
+	Stats() PoolStats
+}
+
+// Exporter is an `http.Handler` that renders the stats of the registered pools
+// in Prometheus text exposition format, labelled by the pools' names.
+type Exporter struct {
+	// lock guards the pools.
+	lock sync.Mutex
+
+	// pools are the registered pools.
+	pools []StatsReporter
+}
+
+// NewExporter creates a new exporter of the given pools' stats.
+func NewExporter(pools ...StatsReporter) *Exporter {
+	return &Exporter{pools: pools}
+}
+
+// Register adds the pools to the exported ones.
+func (e *Exporter) Register(pools ...StatsReporter) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pools = append(e.pools, pools...)
+}
+
+// ServeHTTP renders the stats of the registered pools.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	e.lock.Lock()
+	stats := make([]PoolStats, len(e.pools))
+	for i, pool := range e.pools {
+		stats[i] = pool.Stats()
+	}
+	e.lock.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, stats)
+}
+
+// metric is a single-valued metric of the pool's stats.
+type metric struct {
+	// name is the name of the metric.
+	name string
+
+	// kind is the Prometheus type of the metric.
+	kind string
+
+	// help is the description of the metric.
+	help string
+
+	// value returns the metric's value out of the pool's stats.
+	value func(PoolStats) float64
+}
+
+// metrics are the exported single-valued metrics.
+var metrics = []metric{
+	{"komi_jobs_completed_total", "counter", "Number of jobs completed.",
+		func(s PoolStats) float64 { return float64(s.JobsCompleted) }},
+	{"komi_jobs_succeeded_total", "counter", "Number of jobs completed with nil errors.",
+		func(s PoolStats) float64 { return float64(s.JobsSucceeded) }},
+	{"komi_jobs_failed_total", "counter", "Number of jobs completed with non-nil errors.",
+		func(s PoolStats) float64 { return float64(s.JobsFailed) }},
+	{"komi_jobs_retried_total", "counter", "Number of retries made.",
+		func(s PoolStats) float64 { return float64(s.JobsRetried) }},
+	{"komi_jobs_rejected_total", "counter", "Number of jobs rejected because the pool was full.",
+		func(s PoolStats) float64 { return float64(s.JobsRejected) }},
+	{"komi_jobs_dropped_total", "counter", "Number of jobs dropped or rejected by the overflow policy.",
+		func(s PoolStats) float64 { return float64(s.JobsDropped) }},
+	{"komi_jobs_waiting", "gauge", "Number of jobs waiting in the queue and in-work.",
+		func(s PoolStats) float64 { return float64(s.JobsWaiting) }},
+	{"komi_queue_depth", "gauge", "Number of jobs waiting in the queue.",
+		func(s PoolStats) float64 { return float64(s.QueueDepth) }},
+	{"komi_laborers_busy", "gauge", "Number of laborers performing work.",
+		func(s PoolStats) float64 { return float64(s.LaborersBusy) }},
+	{"komi_laborers_idle", "gauge", "Number of laborers waiting for jobs.",
+		func(s PoolStats) float64 { return float64(s.LaborersIdle) }},
+	{"komi_outputs_backlog", "gauge", "Number of outputs waiting to be read.",
+		func(s PoolStats) float64 { return float64(s.OutputsBacklog) }},
+	{"komi_errors_backlog", "gauge", "Number of errors waiting to be read.",
+		func(s PoolStats) float64 { return float64(s.ErrorsBacklog) }},
+	{"komi_throughput_jobs_per_second", "gauge", "Average number of jobs completed per second.",
+		func(s PoolStats) float64 { return s.Throughput }},
+	{"komi_throttled_seconds_total", "counter", "Total time laborers have waited for the rate limit.",
+		func(s PoolStats) float64 { return s.TimeThrottled.Seconds() }},
+}
+
+// histogramMetric is a histogram metric of the pool's stats.
+type histogramMetric struct {
+	// name is the name of the metric.
+	name string
+
+	// help is the description of the metric.
+	help string
+
+	// value returns the metric's histogram out of the pool's stats.
+	value func(PoolStats) Histogram
+}
+
+// histogramMetrics are the exported histogram metrics.
+var histogramMetrics = []histogramMetric{
+	{"komi_queue_wait_seconds", "How long jobs waited in the queue.",
+		func(s PoolStats) Histogram { return s.QueueWait }},
+	{"komi_execution_seconds", "How long work was performed on jobs.",
+		func(s PoolStats) Histogram { return s.Execution }},
+}
+
+// writeMetrics will write the pools' stats in Prometheus text exposition format.
+func writeMetrics(w io.Writer, stats []PoolStats) {
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range stats {
+			fmt.Fprintf(w, "%s{pool=\"%s\"} %s\n", m.name, escapeLabel(s.Name), formatFloat(m.value(s)))
+		}
+	}
+	for _, m := range histogramMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", m.name, m.help, m.name)
+		for _, s := range stats {
+			pool := escapeLabel(s.Name)
+			h := m.value(s)
+			for _, bucket := range h.Buckets {
+				fmt.Fprintf(w, "%s_bucket{pool=\"%s\",le=\"%s\"} %d\n",
+					m.name, pool, formatFloat(bucket.UpperBound.Seconds()), bucket.Count)
+			}
+			fmt.Fprintf(w, "%s_bucket{pool=\"%s\",le=\"+Inf\"} %d\n", m.name, pool, h.Count)
+			fmt.Fprintf(w, "%s_sum{pool=\"%s\"} %s\n", m.name, pool, formatFloat(h.Sum.Seconds()))
+			fmt.Fprintf(w, "%s_count{pool=\"%s\"} %d\n", m.name, pool, h.Count)
+		}
+	}
+}
+
+// labelEscaper escapes the label values, as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel returns the label value escaped.
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// formatFloat returns the shortest representation of the value.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
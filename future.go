@@ -0,0 +1,95 @@
+package komi
+
+import "context"
+
+// Future is the output and the error of a job submitted with `SubmitFuture`,
+// which become available once the job is performed.
+type Future[O any] struct {
+	// done is closed once the future is resolved.
+	done chan Signal
+
+	// output is the job's output, zero if it failed.
+	output O
+
+	// err is the job's error, nil if it succeeded.
+	err error
+}
+
+// newFuture creates a new unresolved future.
+func newFuture[O any]() *Future[O] {
+	return &Future[O]{done: make(chan Signal)}
+}
+
+// resolve will set the future's output and error, releasing everyone waiting.
+func (f *Future[O]) resolve(output O, err error) {
+	f.output, f.err = output, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the job is performed.
+func (f *Future[O]) Done() <-chan Signal {
+	return f.done
+}
+
+// Get will block until the job is performed or the context is done, returning
+// the job's output and error, or the context's error, respectively. If work
+// failed, the error is the pool error's `Error`.
+func (f *Future[O]) Get(ctx context.Context) (O, error) {
+	select {
+	case <-f.done:
+		return f.output, f.err
+	case <-ctx.Done():
+		return *new(O), ctx.Err()
+	}
+}
+
+// Then returns the future of `next` called with the output of this future, once
+// it's resolved. If the job failed, `next` isn't called and its error is passed on.
+func (f *Future[O]) Then(next func(O) (O, error)) *Future[O] {
+	chained := newFuture[O]()
+	go func() {
+		<-f.done
+		if f.err != nil {
+			chained.resolve(*new(O), f.err)
+			return
+		}
+		chained.resolve(next(f.output))
+	}()
+	return chained
+}
+
+// SubmitFuture sends a job to the pool for processing, see `Submit`, returning the
+// future of its output and error. Unlike other jobs, the output and the error of the
+// job are only given to the future, instead of the outputs and errors channels or
+// handlers. If the job is discarded before it's performed, the future's error is
+// `ErrJobDiscarded`.
+func (p Pool[I, O]) SubmitFuture(job I) (*Future[O], error) {
+	return p.submitFuture(p.ctx, job)
+}
+
+// Do sends a job to the pool for processing and blocks until it's performed, see
+// `SubmitFuture`, returning its output and error. The job's work will receive a
+// context derived from `ctx`, see `SubmitContext`, and if `ctx` is done before the
+// job is performed, its error is returned.
+func (p Pool[I, O]) Do(ctx context.Context, job I) (O, error) {
+	future, err := p.submitFuture(ctx, job)
+	if err != nil {
+		return *new(O), err
+	}
+	return future.Get(ctx)
+}
+
+// submitFuture sends a job submitted with the given context to the pool for
+// processing, returning the future of its output and error.
+func (p Pool[I, O]) submitFuture(ctx context.Context, job I) (*Future[O], error) {
+	release, err := p.openIntake(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	future := newFuture[O]()
+	if err := p.submitWithPolicy(ctx, task[I]{job: job, ctx: ctx, future: future}); err != nil {
+		return nil, err
+	}
+	return future, nil
+}
@@ -0,0 +1,125 @@
+package komi
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// SubmitAll sends the jobs of the sequence to the pool for processing in order,
+// see `Submit`. It stops at the first job that couldn't be submitted, returning
+// its error.
+func (p Pool[I, _]) SubmitAll(jobs iter.Seq[I]) error {
+	i := 0
+	for job := range jobs {
+		if err := p.Submit(job); err != nil {
+			return fmt.Errorf("submitting job %d of the sequence: %w", i, err)
+		}
+		i++
+	}
+	return nil
+}
+
+// Results returns a sequence of outputs and errors (with zero outputs) generated
+// by the pool, read from `Outputs` and `Errors`, which ends once the pool has no
+// waiting jobs and all their outputs and errors have been yielded, or the pool
+// is closed. It yields nothing if the pool is connected or has handlers set.
+func (p Pool[I, O]) Results() iter.Seq2[O, error] {
+	return func(yield func(O, error) bool) {
+		// Nil channels disable their cases.
+		outputs, _ := p.Outputs()
+		errs, _ := p.Errors()
+		for outputs != nil || errs != nil {
+			// Grab the signal before checking, so it can't be missed.
+			noJobsWaiting := p.noJobsWaitingSignal.wait()
+			if (p.JobsWaiting() < 1 || p.ctx.Err() != nil) && len(outputs) < 1 && len(errs) < 1 {
+				return
+			}
+			select {
+			case output, ok := <-outputs:
+				if !ok {
+					outputs = nil
+					continue
+				}
+				if !yield(output, nil) {
+					return
+				}
+			case poolErr, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				if !yield(*new(O), poolErr.Error) {
+					return
+				}
+			case <-noJobsWaiting:
+			case <-p.ctx.Done():
+			}
+		}
+	}
+}
+
+// Stream returns a sequence of outputs and errors (with zero outputs) of `work`
+// performed on the jobs of the given sequence by a new pool with the given settings
+// (can be nil), in the order they complete. The jobs are submitted as the results
+// are iterated over, and the pool is closed once the iteration stops.
+func Stream[I, O any](jobs iter.Seq[I], work func(I) (O, error), settings *Settings) iter.Seq2[O, error] {
+	return func(yield func(O, error) bool) {
+		// The pool's context is cancelled if the iteration stops early.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		tunings := Settings{}
+		if settings != nil {
+			tunings = *settings
+		}
+		tunings.Context = ctx
+		tunings.ConcurrentOutputs = true
+
+		type result struct {
+			output O
+			err    error
+		}
+		results := make(chan result)
+		send := func(r result) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		}
+		pool := NewWithSettings(WorkWithErrors(work).OnOutput(func(output O) {
+			send(result{output: output})
+		}).OnError(func(poolErr PoolError[I]) {
+			send(result{err: poolErr.Error})
+		}), &tunings)
+
+		// Submit the jobs and wait for them, all the results have been
+		// sent by the time the waiting is over.
+		submitted := make(chan Signal)
+		go func() {
+			defer close(submitted)
+			for job := range jobs {
+				if err := pool.Submit(job); err != nil {
+					send(result{err: err})
+					break
+				}
+			}
+			pool.Wait()
+		}()
+		defer func() {
+			cancel()
+			<-submitted
+			pool.Close()
+		}()
+
+		for {
+			select {
+			case r := <-results:
+				if !yield(r.output, r.err) {
+					return
+				}
+			case <-submitted:
+				return
+			}
+		}
+	}
+}
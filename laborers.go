@@ -1,32 +1,167 @@
 package komi
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Submit sends a job to the pool for processing.
 func (p Pool[I, _]) Submit(job I) error {
-	if p.IsClosed() {
-		return errors.New("can't submit a job to the closed pool")
+	return p.SubmitContext(p.ctx, job)
+}
+
+// SubmitContext sends a job to the pool for processing, blocking until it's
+// accepted or either the given or the pool-level context is done, in which
+// case the context's error is returned. If the pool is full, the overflow
+// policy is applied, see `Settings.OverflowPolicy`. The job's work will receive a context
+// derived from `ctx` (if work is context-aware, see `WorkCtx`), and if `ctx` has
+// a deadline, the job will be abandoned after it (see `Settings.JobTimeout`).
+func (p Pool[I, _]) SubmitContext(ctx context.Context, job I) error {
+	release, err := p.openIntake(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.submitWithPolicy(ctx, task[I]{job: job, ctx: ctx})
+}
+
+// submitFromChild sends a job from a connected (child) pool to the pool for
+// processing, see `Submit`, which is taken even if the pool is draining.
+func (p *Pool[I, _]) submitFromChild(job I) error {
+	release, err := p.openIntake(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.submitWithPolicy(p.ctx, task[I]{job: job, ctx: p.ctx})
+}
+
+// TrySubmit sends a job to the pool for processing if there is room for it
+// right away, otherwise, returns false and `ErrPoolFull` without blocking.
+func (p Pool[I, _]) TrySubmit(job I) (bool, error) {
+	release, err := p.openIntake(false)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	err = p.enqueue(p.ctx, task[I]{job: job, ctx: p.ctx}, 0)
+	return err == nil, err
+}
+
+// SubmitTimeout sends a job to the pool for processing, blocking for at most
+// the given duration, after which `ErrPoolFull` is returned.
+func (p Pool[I, _]) SubmitTimeout(job I, timeout time.Duration) error {
+	release, err := p.openIntake(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.enqueue(p.ctx, task[I]{job: job, ctx: p.ctx}, max(timeout, 0))
+}
+
+// enqueue will send the task to the inputs channel, blocking until it's
+// accepted or either the given or the pool-level context is done. If the
+// pool is full for longer than the timeout, `ErrPoolFull` is returned,
+// unless the timeout is negative, in which case it blocks indefinitely.
+func (p *Pool[I, _]) enqueue(ctx context.Context, t task[I], timeout time.Duration) error {
+	t.submitted = time.Now()
+
+	// Try to send the task right away, before setting up any timers.
+	if p.trySend(t) {
+		return nil
+	}
+	if timeout == 0 {
+		p.jobsRejected.Add(1)
+		return ErrPoolFull
+	}
+
+	var full <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		full = timer.C
+	}
+	for {
+		// If the pool is ordered, the task is numbered only once there is room
+		// for it, so waiting submitters don't block the others, they try again
+		// whenever a task is taken from the channel instead.
+		inputs, dequeued := p.inputs, (<-chan Signal)(nil)
+		if p.sequencer != nil {
+			inputs, dequeued = nil, p.sequencer.dequeued.wait()
+			if p.trySend(t) {
+				return nil
+			}
+		}
+		select {
+		case inputs <- t:
+			p.enqueued()
+			return nil
+		case <-dequeued:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		case <-p.intakeStopSignal:
+			return ErrPoolClosed
+		case <-full:
+			p.jobsRejected.Add(1)
+			return ErrPoolFull
+		}
+	}
+}
+
+// trySend will send the task to the inputs channel if there is room for it,
+// without blocking. If the pool is ordered, the task is numbered, and only one
+// task at a time can be sent, so the order of the tasks in the channel matches
+// their numbers.
+func (p *Pool[I, _]) trySend(t task[I]) bool {
+	if p.sequencer != nil {
+		p.sequencer.submitting.Lock()
+		defer p.sequencer.submitting.Unlock()
+		t.seq = p.sequencer.issued
+	}
+	select {
+	case p.inputs <- t:
+		p.enqueued()
+		return true
+	default:
+		return false
+	}
+}
+
+// dequeued will let the submitters waiting for room know the task has been
+// taken from the queue.
+func (p *Pool[_, _]) dequeued() {
+	if p.sequencer != nil {
+		p.sequencer.dequeued.broadcast()
+	}
+}
+
+// enqueued will record a task that has been sent to the inputs channel.
+func (p *Pool[_, _]) enqueued() {
+	if p.sequencer != nil {
+		p.sequencer.issued++
 	}
-	p.inputs <- job
 	p.jobsWaiting.Add(1)
-	return nil
 }
 
 // Outputs returns a channel of outputs generated by the pool, nil
-// if pool is closed or doesn't produce outputs.
+// if pool is closed, doesn't produce outputs, or an output handler is set.
 func (p Pool[_, O]) Outputs() (chan O, error) {
 	if p.IsClosed() {
-		return nil, errors.New("no outputs from a closed pool")
+		return nil, fmt.Errorf("no outputs: %w", ErrPoolClosed)
 	}
 	if !p.producesOutputs() {
 		return nil, errors.New("the pool doesn't produce outputs")
 	}
+	if p.outputHandler != nil {
+		return nil, errors.New("the pool's outputs go to its output handler")
+	}
 	if p.IsConnected() {
-		return nil, fmt.Errorf("the pool is connected to a parent %s", p.parent.Name())
+		return nil, fmt.Errorf("the pool is connected to a parent %s", p.parentsNames())
 	}
 	return p.outputs, nil
 }
@@ -35,74 +170,279 @@ func (p Pool[_, O]) Outputs() (chan O, error) {
 // if pool is closed, doesn't produce errors, or an error handler is set.
 func (p Pool[I, _]) Errors() (chan PoolError[I], error) {
 	if p.IsClosed() {
-		return nil, errors.New("no errors from a closed pool")
+		return nil, fmt.Errorf("no errors: %w", ErrPoolClosed)
 	}
 	if !p.producesErrors() {
 		return nil, errors.New("the pool doesn't produce errors")
 	}
+	if p.errorHandler != nil {
+		return nil, errors.New("the pool's errors go to its error handler")
+	}
 	return p.errors, nil
 }
 
-// startLaborers will start laborers.
-func (p *Pool[I, O]) startLaborers() {
+// startLaborers will start laborers, returning the error of initializing
+// their state, if work has any, in which case, no laborers are started.
+func (p *Pool[I, O]) startLaborers() error {
 	// Create the group to wait on.
 	p.laborersActive = &sync.WaitGroup{}
 
-	// Create the channel
+	// Create the channels
 	p.laborersStopSignal = make(chan Signal)
+	p.laborersRetireSignal = make(chan Signal)
 
-	// Create the number given by the settings.
+	// Create the number given by the settings, initializing all of them
+	// first, so the pool isn't left with some of them if any init fails.
+	laborers := make([]*laborer[I, O], 0, p.settings.Laborers)
 	for i := 0; i < p.settings.Laborers; i++ {
-		go func(p *Pool[I, O]) {
-			for {
-				select {
-				case job := <-p.inputs:
-					// Run the work performer on each new job.
-					p.workPerformer(job)
-					continue
-				case <-p.laborersStopSignal:
-					// When stop signal received, mark the laborer as inactive
-					// and kill the current scope.
-					p.laborersActive.Done()
+		l, err := p.newLaborer()
+		if err != nil {
+			for _, l := range laborers {
+				p.retireLaborer(l)
+			}
+			return err
+		}
+		laborers = append(laborers, l)
+	}
+	p.laborersWanted.Store(int64(p.settings.Laborers))
+	for _, l := range laborers {
+		p.startLaborer(l)
+	}
+	p.log.Debug("Started laborers", "count", p.settings.Laborers)
+
+	// If requested, adjust the number of laborers to the load.
+	if p.settings.Autoscale != nil {
+		go p.autoscale()
+	}
+	return nil
+}
+
+// spawnLaborer records a new active laborer and starts it, the laborer
+// initializes its own state, if work has any.
+func (p *Pool[I, O]) spawnLaborer() {
+	p.startLaborer(nil)
+}
+
+// startLaborer records a new active laborer and starts it.
+func (p *Pool[I, O]) startLaborer(l *laborer[I, O]) {
+	p.laborersActive.Add(1)
+	p.laborersCount.Add(1)
+	go p.labor(l)
+}
+
+// labor is the laborer's loop, which performs work on incoming jobs until
+// either the stop signal is received or the pool-level context is done.
+// If the laborer is nil, it's initialized first.
+func (p *Pool[I, O]) labor(l *laborer[I, O]) {
+	// When leaving, mark the laborer as inactive.
+	defer p.laborersActive.Done()
+	defer p.laborersCount.Add(-1)
+	if l == nil {
+		var err error
+		if l, err = p.newLaborer(); err != nil {
+			p.laborersWanted.Add(-1)
+			p.laborerFailed(err)
+			return
+		}
+	}
+	// Release the laborer's state once it leaves.
+	defer p.retireLaborer(l)
+
+	// If requested, the laborer is recycled once it's old enough.
+	var aging *time.Timer
+	var expired <-chan time.Time
+	if p.settings.LaborerMaxAge > 0 {
+		aging = time.NewTimer(p.settings.LaborerMaxAge)
+		defer aging.Stop()
+		expired = aging.C
+	}
+	jobs := 0
+	for {
+		// Don't pick up any more jobs if the pool's context is done.
+		if p.ctx.Err() != nil {
+			return
+		}
+		select {
+		case t := <-p.ready:
+			p.dequeued()
+
+			// Leave the job for the closure, if it's been picked up
+			// after the laborers were told to quit.
+			select {
+			case <-p.laborersStopSignal:
+				p.leftovers.put(t)
+				return
+			default:
+			}
+
+			// Record how long the job has been waiting in the queue.
+			p.recordQueueWait(t)
+
+			var panicked bool
+			if p.isWorkBatch() {
+				// Gather more jobs to perform together with this one.
+				var performed int
+				performed, panicked = p.laborBatch(t)
+				jobs += performed
+			} else {
+				// If the pool is ordered, don't run too far ahead of the
+				// oldest job, whose outcome hasn't been released yet.
+				if p.sequencer != nil {
+					p.sequencer.admit(t.seq)
+				}
+				// Run the work performer on each new job.
+				p.laborersBusy.Add(1)
+				panicked = p.perform(l, t)
+				p.laborersBusy.Add(-1)
+				if p.scheduler != nil {
+					p.scheduler.finished(t)
+				}
+				jobs++
+			}
+			if l.abandoned != nil {
+				// The abandoned job might still be using the laborer's state,
+				// so replace the laborer, instead of sharing the state.
+				p.log.Warn("Replacing the laborer after abandoning its job", "laborer", l.id)
+				p.replaceLaborerPatiently()
+				return
+			}
+			if panicked && p.settings.RestartOnPanic {
+				// Work panicked, so replace this laborer with a fresh
+				// one, as requested by the settings.
+				p.log.Warn("Restarting the laborer after a panic")
+				if p.recycle() {
+					return
+				}
+			} else if p.settings.LaborerMaxJobs > 0 && jobs >= p.settings.LaborerMaxJobs {
+				p.log.Debug("Recycling the laborer after its jobs", "laborer", l.id, "jobs", jobs)
+				if p.recycle() {
+					return
+				}
+				jobs = 0
+			}
+			// Don't pick up another job if the laborer got old meanwhile.
+			select {
+			case <-expired:
+				p.log.Debug("Recycling the laborer after its age", "laborer", l.id, "jobs", jobs)
+				if p.recycle() {
 					return
 				}
+				aging.Reset(p.settings.LaborerMaxAge)
+			default:
 			}
-		}(p)
+		case <-expired:
+			p.log.Debug("Recycling the laborer after its age", "laborer", l.id, "jobs", jobs)
+			if p.recycle() {
+				return
+			}
+			aging.Reset(p.settings.LaborerMaxAge)
+		case <-p.laborersRetireSignal:
+			// The pool is shrinking, so this laborer is no longer needed.
+			return
+		case <-p.laborersStopSignal:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
 
-		// Record the laborer as an active laborer.
-		p.laborersActive.Add(1)
+// recycle will replace the laborer with a fresh one, returning true if the laborer
+// should leave. If the fresh laborer fails to initialize, the error is reported, and
+// the laborer should keep working, so the pool doesn't lose laborers.
+func (p *Pool[I, O]) recycle() bool {
+	if err := p.replaceLaborer(); err != nil {
+		p.laborerFailed(err)
+		return false
 	}
-	p.log.Debug("Started laborers", "count", p.settings.Laborers)
+	return true
 }
 
-// stopLaborers will send blocking closure signals to all laborers and wait (blocking)
-// until they all gracefully leave.
-func (p *Pool[_, _]) stopLaborers() {
-	p.log.Debug("Sending signals to kill laborers...", "count", p.settings.Laborers)
-	for i := 0; i < p.settings.Laborers; i++ {
-		p.laborersStopSignal <- signal
+// replaceLaborerPatiently will replace the laborer, which can't keep working, with
+// a fresh one, reporting the failures to initialize it and backing off between the
+// attempts, until it starts or the laborer is no longer needed. The leaving laborer
+// should return right after.
+func (p *Pool[I, O]) replaceLaborerPatiently() {
+	for attempt := 1; ; attempt++ {
+		err := p.replaceLaborer()
+		if err == nil {
+			return
+		}
+		p.laborerFailed(err)
+		timer := time.NewTimer(laborerInitRetry.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-p.laborersRetireSignal:
+			// The pool is shrinking, so no replacement is needed.
+			timer.Stop()
+			return
+		case <-p.laborersStopSignal:
+			timer.Stop()
+			return
+		case <-p.ctx.Done():
+			timer.Stop()
+			return
+		}
 	}
+}
 
-	// Wait for all the laborers to quit.
-	p.laborersActive.Wait()
+// replaceLaborer will initialize a fresh laborer and start it in place of the
+// leaving one, unless laborers have been told to quit, so the number of laborers
+// stays the same. Returns the error of initializing it, in which case nothing is
+// started. Otherwise, the leaving laborer should return right after.
+func (p *Pool[I, O]) replaceLaborer() error {
+	l, err := p.newLaborer()
+	if err != nil {
+		return err
+	}
+	p.laborersLock.Lock()
+	select {
+	case <-p.laborersStopSignal:
+		p.laborersLock.Unlock()
+		p.retireLaborer(l)
+		return nil
+	default:
+	}
+	p.startLaborer(l)
+	p.laborersLock.Unlock()
+	return nil
+}
+
+// stopLaborers will send closure signals to all laborers and wait (blocking)
+// until they all gracefully leave, tearing down their state, if work has any.
+func (p *Pool[_, _]) stopLaborers() {
+	p.log.Debug("Sending signals to kill laborers...", "count", p.Laborers())
 
-	// After laborers quit, close the channel.
+	// Closing the channel will broadcast the signal to all laborers, including
+	// the ones that have already left because the pool's context is done. It's
+	// done while holding the lock, so no new laborers are spawned after this.
+	p.laborersLock.Lock()
 	close(p.laborersStopSignal)
+	p.laborersLock.Unlock()
+
+	// Wait for all the laborers to quit.
+	p.laborersActive.Wait()
 
 	// Log the laborers closure.
-	p.log.Debug("All laborers quit", "count", p.settings.Laborers)
+	p.log.Debug("All laborers quit")
 }
 
 // Wait wil block until the pool has no waiting jobs, see `With...` options.
 func (p Pool[_, _]) Wait() {
-	p.currentlyWaitingForJobs.Store(true)
-	defer p.currentlyWaitingForJobs.Store(false)
-	// If no jobs waiting, leave immediately
-	if p.JobsWaiting() == 0 {
-		return
+	for p.JobsWaiting() > 0 {
+		// Grab the signal before checking again, so it can't be missed.
+		noJobsWaiting := p.noJobsWaitingSignal.wait()
+		if p.JobsWaiting() < 1 {
+			return
+		}
+		// Wait for the `performedWork` to send a signal, laborers won't
+		// pick up any jobs if the pool's context is done.
+		select {
+		case <-noJobsWaiting:
+		case <-p.ctx.Done():
+			return
+		}
 	}
-	// Wait for the `performedWork` to send a signal
-	<-p.noJobsCurrentlyWaitingSignal
 }
 
 // JobsCompleted will return the number of jobs completed by the pool.
@@ -120,3 +460,8 @@ func (p Pool[_, _]) JobsWaiting() int64 {
 func (p Pool[_, _]) JobsSucceeded() int64 {
 	return p.jobsSucceeded.Load()
 }
+
+// JobsRejected will return the number of jobs rejected because the pool was full.
+func (p Pool[_, _]) JobsRejected() int64 {
+	return p.jobsRejected.Load()
+}
@@ -0,0 +1,88 @@
+package komi
+
+// State is the pool's lifecycle state, it only moves forward.
+type State int32
+
+const (
+	// StateRunning is the state of a pool that takes and performs jobs.
+	StateRunning State = iota
+
+	// StateDraining is the state of a pool that is finishing its jobs and
+	// its children's before closing, it only takes jobs from its children.
+	StateDraining
+
+	// StateClosing is the state of a pool whose laborers are quitting and
+	// channels are closing, it takes no jobs.
+	StateClosing
+
+	// StateClosed is the state of a fully closed pool.
+	StateClosed
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the pool's lifecycle state.
+func (p Pool[_, _]) State() State {
+	return State(p.state.Load())
+}
+
+// setState moves the pool to the given state.
+func (p *Pool[_, _]) setState(state State) {
+	p.state.Store(int32(state))
+	p.log.Debug("Pool state changed", "state", state)
+}
+
+// stateErr returns `ErrPoolDraining` or `ErrPoolClosed` if the pool's state
+// doesn't allow taking jobs, connected (child) pools can still send jobs to
+// a draining pool.
+func (p Pool[_, _]) stateErr(fromChild bool) error {
+	switch state := p.State(); {
+	case state == StateRunning:
+		return nil
+	case state == StateDraining && fromChild:
+		return nil
+	case state == StateDraining:
+		return ErrPoolDraining
+	default:
+		return ErrPoolClosed
+	}
+}
+
+// openIntake will hold the intake open for a submission, returning a non-nil error
+// if the pool can't take any jobs. Otherwise, the returned function must be called
+// once the submission is over, so the pool can close the inputs channel safely.
+func (p Pool[_, _]) openIntake(fromChild bool) (func(), error) {
+	p.intake.RLock()
+	if err := p.stateErr(fromChild); err != nil {
+		p.intake.RUnlock()
+		return nil, err
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.intake.RUnlock()
+		return nil, err
+	}
+	return p.intake.RUnlock, nil
+}
+
+// stopIntake will move the pool to the closing state, releasing the blocked
+// submitters and waiting (blocking) for all the submitters to leave.
+func (p *Pool[_, _]) stopIntake() {
+	p.setState(StateClosing)
+	close(p.intakeStopSignal)
+	p.intake.Lock()
+	defer p.intake.Unlock()
+}
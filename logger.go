@@ -0,0 +1,48 @@
+package komi
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// newLogger creates the pool's logger as requested by the settings, also
+// returning the default logger handling it, nil if the user supplied their own
+// logger or handler, or disabled logging.
+func newLogger(settings *Settings) (*slog.Logger, *log.Logger) {
+	if settings.DisableLogging {
+		return slog.New(discardHandler{}), nil
+	}
+	if settings.Logger != nil {
+		return settings.Logger.With("pool", settings.Name), nil
+	}
+	if settings.LogHandler != nil {
+		return slog.New(settings.LogHandler).With("pool", settings.Name), nil
+	}
+	defaultLog := log.NewWithOptions(os.Stderr, log.Options{
+		TimeFormat:      time.DateTime,
+		ReportTimestamp: true,
+		ReportCaller:    false,
+		Level:           settings.LogLevel,
+		Prefix:          settings.Name,
+	})
+	return slog.New(defaultLog), defaultLog
+}
+
+// discardHandler is a log handler that discards all the records.
+type discardHandler struct{}
+
+// Enabled returns false, so no records are made.
+func (discardHandler) Enabled(context.Context, slog.Level) bool { return false }
+
+// Handle discards the record.
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+
+// WithAttrs returns the same handler.
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup returns the same handler.
+func (h discardHandler) WithGroup(string) slog.Handler { return h }
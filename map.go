@@ -0,0 +1,108 @@
+package komi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// Map will run the items through a new pool with the given settings (can be nil)
+// performing `work` on each, and return the outputs in the order of the items,
+// leaving zero values in place of the failed ones. The pool errors are returned
+// in the order of the items too. If the context is done before all the items
+// are performed, the remaining ones fail with the context's error. The pool is
+// closed before returning.
+func Map[I, O any](ctx context.Context, items []I, work func(I) (O, error), settings *Settings) ([]O, []PoolError[I]) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	outputs := make([]O, len(items))
+	if len(items) < 1 {
+		return outputs, nil
+	}
+
+	// Don't change the user's settings, the pool's context is the given one.
+	tunings := Settings{}
+	if settings != nil {
+		tunings = *settings
+	}
+	tunings.Context = ctx
+	tunings.ConcurrentOutputs = true
+
+	// Every item is numbered, so outputs and errors can be put in their places.
+	performed := make([]bool, len(items))
+	failed := []indexed[PoolError[I]]{}
+	failedLock := &sync.Mutex{}
+	pool := NewWithSettings(WorkWithErrors(func(item indexed[I]) (indexed[O], error) {
+		output, err := work(item.value)
+		return indexed[O]{index: item.index, value: output}, err
+	}).OnOutput(func(output indexed[O]) {
+		outputs[output.index] = output.value
+		performed[output.index] = true
+	}).OnError(func(poolErr PoolError[indexed[I]]) {
+		failedLock.Lock()
+		defer failedLock.Unlock()
+		failed = append(failed, indexed[PoolError[I]]{index: poolErr.Job.index, value: PoolError[I]{
+			Job:            poolErr.Job.value,
+			Error:          poolErr.Error,
+			Panic:          poolErr.Panic,
+			Stack:          poolErr.Stack,
+			Attempts:       poolErr.Attempts,
+			PreviousErrors: poolErr.PreviousErrors,
+		}})
+		performed[poolErr.Job.index] = true
+	}), &tunings)
+
+	// Items that couldn't be submitted fail with the submission error.
+	for i, item := range items {
+		if err := pool.Submit(indexed[I]{index: i, value: item}); err != nil {
+			failedLock.Lock()
+			failed = append(failed, indexed[PoolError[I]]{index: i, value: PoolError[I]{Job: item, Error: err}})
+			failedLock.Unlock()
+			performed[i] = true
+		}
+	}
+	pool.Wait()
+	pool.Close()
+
+	// Items left behind, because the context is done or the overflow
+	// policy dropped them, fail with the reason.
+	left := context.Cause(ctx)
+	if left == nil {
+		left = errors.New("the job was dropped by the overflow policy")
+	}
+	for i, item := range items {
+		if !performed[i] {
+			failed = append(failed, indexed[PoolError[I]]{index: i, value: PoolError[I]{Job: item, Error: left}})
+		}
+	}
+	if len(failed) < 1 {
+		return outputs, nil
+	}
+	slices.SortFunc(failed, func(a, b indexed[PoolError[I]]) int { return a.index - b.index })
+	errs := make([]PoolError[I], len(failed))
+	for i, poolErr := range failed {
+		errs[i] = poolErr.value
+	}
+	return outputs, errs
+}
+
+// ForEach will run the items through a new pool with the given settings (can be nil)
+// performing `work` on each, and return the pool errors in the order of the items,
+// see `Map`.
+func ForEach[I any](ctx context.Context, items []I, work func(I) error, settings *Settings) []PoolError[I] {
+	_, errs := Map(ctx, items, func(item I) (Signal, error) {
+		return signal, work(item)
+	}, settings)
+	return errs
+}
+
+// indexed is a value numbered by its place in a slice.
+type indexed[T any] struct {
+	// index is the place of the value.
+	index int
+
+	// value is the value itself.
+	value T
+}
@@ -1,5 +1,7 @@
 package komi
 
+import "context"
+
 // poolWork is an internal function type to set pool's work performer.
 type poolWork[I, O any] func(p *Pool[I, O])
 
@@ -38,3 +40,91 @@ func WorkWithErrors[I, O any](work func(I) (O, error)) poolWork[I, O] {
 		p.workPerformer = p.performWorkWithErrors
 	}
 }
+
+// WorkCtx should be used to set context-aware work with both outputs and errors.
+// The context given to work is derived from the context the job was submitted
+// with and is cancelled when the pool-level context is cancelled.
+func WorkCtx[I, O any](work func(context.Context, I) (O, error)) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		p.workCtx = work
+		p.workPerformer = p.performWorkCtx
+	}
+}
+
+// WorkBatch should be used to set work performed on batches of jobs, with both outputs
+// and errors. Laborers group the queued jobs into batches of up to `Settings.BatchSize`
+// jobs, waiting for up to `Settings.BatchLinger` for the batch to fill up. Work should
+// return an output for every job, in the same order, and an error failing all of the
+// jobs, or `BatchErrors` failing only some of them. Retries and job timeouts don't
+// apply to batches, and each batch counts as one job against the rate limit.
+func WorkBatch[I, O any](work func([]I) ([]O, error)) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		p.workBatch = work
+		p.workPerformer = p.performWorkBatch
+	}
+}
+
+// WorkWithState should be used to set work with both outputs and errors, which
+// needs its own state in every laborer, like a connection or a client, that
+// shouldn't be shared by laborers or made for every job. The state is made by `init`
+// with the laborer's ID, before the laborer picks up any jobs, and released by
+// `teardown` (can be nil) once the laborer quits. IDs are unique among the running
+// laborers, the lowest free one is given to a new laborer. If `init` fails for the
+// laborers the pool starts with, the pool isn't created, and the error is panicked
+// with. Later, the error (wrapping `ErrLaborerInit`) is reported as a pool error
+// without a job: a laborer being replaced keeps working, while a laborer being added
+// is not started. A laborer abandoning a job after its deadline is replaced (the
+// replacement's init is retried with a backoff), and its state is only released
+// once the abandoned job returns.
+func WorkWithState[S, I, O any](init func(laborerID int) (S, error), work func(S, I) (O, error), teardown func(S)) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		p.workState = func(id int) (func(context.Context, I) (O, error), func(), error) {
+			state, err := init(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			perform := func(_ context.Context, job I) (O, error) {
+				return work(state, job)
+			}
+			release := func() {
+				if teardown != nil {
+					teardown(state)
+				}
+			}
+			return perform, release, nil
+		}
+	}
+}
+
+// OnOutput sets the handler that will be called with every output, instead of
+// sending it to the outputs channel (see `Outputs`), so outputs don't have to be
+// consumed separately. The handler is called by the laborer that performed the job,
+// one call at a time, unless `Settings.ConcurrentOutputs` is set. A pool with an
+// output handler can't be connected to other pools.
+func (w poolWork[I, O]) OnOutput(handler func(O)) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		w(p)
+		p.outputHandler = handler
+	}
+}
+
+// OnError sets the handler that will be called with every pool error, instead of
+// sending it to the errors channel (see `Errors`), so errors don't have to be consumed
+// separately. It also receives panics of work that doesn't produce errors. The handler
+// is called by the laborer that performed the job, so it may be called concurrently.
+func (w poolWork[I, O]) OnError(handler func(PoolError[I])) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		w(p)
+		p.errorHandler = handler
+	}
+}
+
+// OnDrop sets the handler that will be called with every job dropped or rejected
+// by the overflow policy, see `Settings.OverflowPolicy`. The handler is called by
+// the submitter and should not block.
+func (w poolWork[I, O]) OnDrop(handler func(I)) poolWork[I, O] {
+	return func(p *Pool[I, O]) {
+		w(p)
+		p.dropHandler = handler
+	}
+}
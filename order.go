@@ -0,0 +1,98 @@
+package komi
+
+import "sync"
+
+// sequencer numbers the submitted jobs and releases their outcomes in
+// submission order, no matter in which order laborers complete them.
+type sequencer struct {
+	// submitting is held while a job is numbered and sent to the inputs channel,
+	// so the order of the jobs in the channel matches their sequence numbers.
+	// It's never held while waiting for room in the channel.
+	submitting sync.Mutex
+
+	// issued is the sequence number of the next submitted job.
+	issued uint64
+
+	// dequeued is broadcast when a job is taken from the inputs channel, so the
+	// submitters waiting for room can try again.
+	dequeued *broadcaster
+
+	// lock guards the fields below.
+	lock sync.Mutex
+
+	// advanced is broadcast when the next released sequence number moves forward.
+	advanced *sync.Cond
+
+	// next is the sequence number of the next outcome to release.
+	next uint64
+
+	// window is how far ahead of the next released job laborers can work, which
+	// bounds the number of outcomes held back.
+	window uint64
+
+	// held are the outcomes of completed jobs waiting for the earlier ones.
+	held map[uint64]func()
+
+	// releasing is true while a laborer is running the released outcomes, which
+	// is done outside of the lock, so only one of them runs them at a time.
+	releasing bool
+}
+
+// newSequencer creates a new sequencer with the given reorder window.
+func newSequencer(window int) *sequencer {
+	s := &sequencer{
+		window:   uint64(window),
+		held:     map[uint64]func(){},
+		dequeued: newBroadcaster(),
+	}
+	s.advanced = sync.NewCond(&s.lock)
+	return s
+}
+
+// admit will block until the job with the sequence number is within the
+// reorder window, so laborers don't run too far ahead of the released outcomes.
+func (s *sequencer) admit(seq uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for seq >= s.next+s.window {
+		s.advanced.Wait()
+	}
+}
+
+// admits returns true if the job with the sequence number is within the
+// reorder window, so it can be worked on without waiting.
+func (s *sequencer) admits(seq uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return seq < s.next+s.window
+}
+
+// complete will hold the job's outcome (can be nil) until all the earlier
+// jobs' outcomes are released, and then release all the consecutive ones.
+// Outcomes run outside of the lock, as they can block on a full channel,
+// unless another laborer is already running them, which then runs this one.
+func (s *sequencer) complete(seq uint64, outcome func()) {
+	s.lock.Lock()
+	s.held[seq] = outcome
+	if s.releasing {
+		s.lock.Unlock()
+		return
+	}
+	s.releasing = true
+	for {
+		outcome, ok := s.held[s.next]
+		if !ok {
+			break
+		}
+		delete(s.held, s.next)
+		s.lock.Unlock()
+		if outcome != nil {
+			outcome()
+		}
+		s.lock.Lock()
+		s.next++
+		s.advanced.Broadcast()
+	}
+	s.releasing = false
+	s.lock.Unlock()
+}
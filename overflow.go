@@ -0,0 +1,231 @@
+package komi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what happens to jobs submitted to a full pool.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the submission until there is room for the job.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowRejectNewest rejects the submitted job with `ErrPoolFull`.
+	OverflowRejectNewest
+
+	// OverflowDropOldest drops the oldest job waiting in the queue to make
+	// room for the submitted job.
+	OverflowDropOldest
+
+	// OverflowSpill puts the submitted job to an unbounded overflow list, which
+	// feeds the queue as soon as there is room, keeping the submission order.
+	OverflowSpill
+)
+
+// DeadLetters returns a channel of jobs dropped or rejected by the overflow policy,
+// nil if pool is closed or dead letters are not enabled in settings.
+func (p Pool[I, _]) DeadLetters() (chan I, error) {
+	if p.IsClosed() {
+		return nil, fmt.Errorf("no dead letters: %w", ErrPoolClosed)
+	}
+	if p.deadLetters == nil {
+		return nil, errors.New("the pool doesn't have dead letters enabled")
+	}
+	return p.deadLetters, nil
+}
+
+// JobsDropped will return the number of jobs dropped or rejected by the overflow policy.
+func (p Pool[_, _]) JobsDropped() int64 {
+	return p.jobsDropped.Load()
+}
+
+// submitWithPolicy will send the task to the inputs channel, applying the
+// overflow policy if the pool is full.
+func (p *Pool[I, _]) submitWithPolicy(ctx context.Context, t task[I]) error {
+	switch p.settings.OverflowPolicy {
+	case OverflowRejectNewest:
+		err := p.enqueue(ctx, t, 0)
+		if errors.Is(err, ErrPoolFull) {
+			p.drop(t.job)
+		}
+		return err
+	case OverflowDropOldest:
+		// The scheduler drops the oldest jobs in the priority queue itself.
+		if p.scheduler != nil {
+			return p.enqueue(ctx, t, -1)
+		}
+		p.enqueueDroppingOldest(t)
+		return nil
+	case OverflowSpill:
+		p.spill(t)
+		return nil
+	default:
+		return p.enqueue(ctx, t, -1)
+	}
+}
+
+// enqueueDroppingOldest will send the task to the inputs channel, dropping
+// the oldest waiting tasks until there is room for it.
+func (p *Pool[I, _]) enqueueDroppingOldest(t task[I]) {
+	if p.sequencer != nil {
+		p.sequencer.submitting.Lock()
+		defer p.sequencer.submitting.Unlock()
+		t.seq = p.sequencer.issued
+	}
+	t.submitted = time.Now()
+	for {
+		select {
+		case p.inputs <- t:
+			p.enqueued()
+			return
+		default:
+		}
+		// Laborers may take the oldest one first, in which case, try again.
+		select {
+		case oldest := <-p.inputs:
+			p.unqueued(oldest)
+			p.drop(oldest.job)
+		default:
+		}
+	}
+}
+
+// unqueued will record a task that was removed from the queue without
+// performing any work on it.
+func (p *Pool[I, O]) unqueued(t task[I]) {
+	if future, ok := t.future.(*Future[O]); ok {
+		future.resolve(*new(O), ErrJobDiscarded)
+	}
+	// An ordered pool shouldn't wait for the outcome of this task.
+	p.release(t, nil)
+	if p.jobsWaiting.Add(-1) < 1 {
+		p.noJobsWaitingSignal.broadcast()
+	}
+}
+
+// drop will hand the job over to the drop handler and dead letters, or log
+// it, if neither is set, so no job disappears silently.
+func (p *Pool[I, _]) drop(job I) {
+	p.jobsDropped.Add(1)
+	if p.dropHandler != nil {
+		p.dropHandler(job)
+	}
+	if p.deadLetters != nil {
+		select {
+		case p.deadLetters <- job:
+		default:
+			p.log.Warn("Dead letters are full, lost a dropped job", "job", job)
+		}
+	}
+	if p.dropHandler == nil && p.deadLetters == nil {
+		p.log.Warn("Dropped a job, because the pool is full", "job", job)
+	}
+}
+
+// overflow is an unbounded list of tasks waiting for room in the inputs channel.
+type overflow[I any] struct {
+	// lock guards the tasks and the pending count.
+	lock sync.Mutex
+
+	// tasks are the spilled tasks, oldest first.
+	tasks []task[I]
+
+	// pending is the number of spilled tasks, including the one being moved
+	// to the inputs channel, which is not in tasks anymore.
+	pending int
+
+	// spilled signals the feeder that there are new tasks.
+	spilled chan Signal
+
+	// fed is closed when the feeder quits.
+	fed chan Signal
+}
+
+// spill will send the task to the inputs channel if there is room and nothing
+// has been spilled before it, otherwise, adds it to the overflow list.
+func (p *Pool[I, _]) spill(t task[I]) {
+	if p.sequencer != nil {
+		p.sequencer.submitting.Lock()
+		defer p.sequencer.submitting.Unlock()
+		t.seq = p.sequencer.issued
+	}
+	t.submitted = time.Now()
+	p.overflow.lock.Lock()
+	defer p.overflow.lock.Unlock()
+	if p.overflow.pending == 0 {
+		select {
+		case p.inputs <- t:
+			p.enqueued()
+			return
+		default:
+		}
+	}
+	p.overflow.tasks = append(p.overflow.tasks, t)
+	p.overflow.pending++
+	p.enqueued()
+	select {
+	case p.overflow.spilled <- signal:
+	default:
+	}
+}
+
+// feedOverflow will move the spilled tasks to the inputs channel in order,
+// until laborers are told to quit or the pool's context is done.
+func (p *Pool[I, _]) feedOverflow() {
+	defer close(p.overflow.fed)
+	for {
+		p.overflow.lock.Lock()
+		if len(p.overflow.tasks) < 1 {
+			p.overflow.lock.Unlock()
+			select {
+			case <-p.overflow.spilled:
+				continue
+			case <-p.laborersStopSignal:
+				return
+			case <-p.ctx.Done():
+				return
+			}
+		}
+		t := p.overflow.tasks[0]
+		p.overflow.tasks = p.overflow.tasks[1:]
+		p.overflow.lock.Unlock()
+
+		select {
+		case p.inputs <- t:
+		case <-p.laborersStopSignal:
+			p.overflow.unshift(t)
+			return
+		case <-p.ctx.Done():
+			p.overflow.unshift(t)
+			return
+		}
+
+		p.overflow.lock.Lock()
+		p.overflow.pending--
+		p.overflow.lock.Unlock()
+	}
+}
+
+// unshift will put the task back to the front of the overflow list.
+func (o *overflow[I]) unshift(t task[I]) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.tasks = append([]task[I]{t}, o.tasks...)
+}
+
+// takeOverflow will wait for the feeder to quit and take the tasks still
+// in the overflow list, oldest first.
+func (p *Pool[I, _]) takeOverflow() []task[I] {
+	<-p.overflow.fed
+	p.overflow.lock.Lock()
+	defer p.overflow.lock.Unlock()
+	tasks := p.overflow.tasks
+	p.overflow.tasks = nil
+	p.overflow.pending = 0
+	return tasks
+}
@@ -0,0 +1,349 @@
+package komi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Stage is a pool with its types hidden, so pools of different types can be
+// registered in the same pipeline. All pools satisfy it.
+type Stage interface {
+	// Name returns the name of the pool.
+	Name() string
+
+	// JobsCompleted returns the number of jobs completed by the pool.
+	JobsCompleted() int64
+
+	// JobsWaiting returns the number of jobs waiting and executing by the pool.
+	JobsWaiting() int64
+
+	// JobsSucceeded returns the number of jobs succeeded by the pool.
+	JobsSucceeded() int64
+
+	// Wait blocks until the pool has no waiting jobs.
+	Wait()
+
+	// Close issues a pool closure request.
+	Close(force ...bool) error
+
+	// IsClosed returns true if the pool is closed.
+	IsClosed() bool
+
+	// accepts returns true if the job can be submitted to the pool.
+	accepts(job any) bool
+
+	// submitAny submits the job to the pool, if it's of pool's input type.
+	submitAny(job any) error
+
+	// canConnectTo returns a non-nil error if the pool's outputs can't be
+	// sent as jobs to the parent.
+	canConnectTo(parent Stage) error
+
+	// connectTo connects the pool to the parents, broadcasting its outputs
+	// if there is more than one.
+	connectTo(parents []Stage) error
+}
+
+// StageStats is a snapshot of pipeline stage's counters.
+type StageStats struct {
+	// Name is the name the stage was registered with.
+	Name string
+
+	// JobsCompleted is the number of jobs completed by the stage.
+	JobsCompleted int64
+
+	// JobsWaiting is the number of jobs waiting and executing by the stage.
+	JobsWaiting int64
+
+	// JobsSucceeded is the number of jobs succeeded by the stage.
+	JobsSucceeded int64
+}
+
+// Pipeline is a directed acyclic graph of named pools, where edges connect
+// pool's outputs to another pool's inputs. Once built, jobs are submitted to
+// source stages (the ones nothing is sent to) and the whole graph can be
+// waited on and closed at once.
+type Pipeline struct {
+	// stages are the registered stages by their names.
+	stages map[string]Stage
+
+	// names are the registered stages' names in registration order.
+	names []string
+
+	// edges are the names of stages each stage sends its outputs to.
+	edges map[string][]string
+
+	// errs are the errors of registering stages and edges, reported by `Build`.
+	errs []error
+
+	// built is true after the pipeline has been successfully built.
+	built bool
+
+	// order are the stages' names in topological order, sources first.
+	order []string
+
+	// sources are the names of the stages that no stage sends outputs to.
+	sources []string
+
+	// sinks are the names of the stages that send their outputs to no stage.
+	sinks []string
+}
+
+// NewPipeline creates a new empty pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{
+		stages: map[string]Stage{},
+		edges:  map[string][]string{},
+	}
+}
+
+// Add registers the pool under the name, errors are reported by `Build`.
+func (pl *Pipeline) Add(name string, stage Stage) *Pipeline {
+	switch {
+	case pl.built:
+		pl.errs = append(pl.errs, fmt.Errorf("can't add stage %q to a built pipeline", name))
+	case stage == nil:
+		pl.errs = append(pl.errs, fmt.Errorf("stage %q is nil", name))
+	case pl.stages[name] != nil:
+		pl.errs = append(pl.errs, fmt.Errorf("stage %q is already added", name))
+	default:
+		pl.stages[name] = stage
+		pl.names = append(pl.names, name)
+	}
+	return pl
+}
+
+// Connect registers an edge, so outputs of stage `from` are sent as jobs to
+// stage `to`, errors are reported by `Build`.
+func (pl *Pipeline) Connect(from, to string) *Pipeline {
+	switch {
+	case pl.built:
+		pl.errs = append(pl.errs, fmt.Errorf("can't connect %q to %q in a built pipeline", from, to))
+	case from == to:
+		pl.errs = append(pl.errs, fmt.Errorf("can't connect stage %q to itself", from))
+	default:
+		for _, existing := range pl.edges[from] {
+			if existing == to {
+				pl.errs = append(pl.errs, fmt.Errorf("stage %q is already connected to %q", from, to))
+				return pl
+			}
+		}
+		pl.edges[from] = append(pl.edges[from], to)
+	}
+	return pl
+}
+
+// Build validates the stages and edges, making sure every edge connects
+// matching types and there are no cycles, and then connects the pools.
+func (pl *Pipeline) Build() error {
+	if pl.built {
+		return errors.New("the pipeline is already built")
+	}
+	if err := pl.validate(); err != nil {
+		return err
+	}
+	for _, name := range pl.order {
+		targets := pl.edges[name]
+		if len(targets) < 1 {
+			continue
+		}
+		parents := make([]Stage, len(targets))
+		for i, target := range targets {
+			parents[i] = pl.stages[target]
+		}
+		if err := pl.stages[name].connectTo(parents); err != nil {
+			return fmt.Errorf("connecting stage %q: %w", name, err)
+		}
+	}
+	pl.built = true
+	return nil
+}
+
+// validate checks the registered stages and edges, setting the topological
+// order, sources, and sinks of the pipeline.
+func (pl *Pipeline) validate() error {
+	errs := pl.errs
+	if len(pl.stages) < 1 {
+		errs = append(errs, errors.New("the pipeline has no stages"))
+	}
+
+	// Check that edges reference known stages with matching types.
+	incoming := map[string]int{}
+	for _, from := range pl.names {
+		for _, to := range pl.edges[from] {
+			if pl.stages[to] == nil {
+				errs = append(errs, fmt.Errorf("stage %q is connected to unknown stage %q", from, to))
+				continue
+			}
+			if err := pl.stages[from].canConnectTo(pl.stages[to]); err != nil {
+				errs = append(errs, fmt.Errorf("can't connect stage %q to %q: %w", from, to, err))
+			}
+			incoming[to]++
+		}
+	}
+	for from := range pl.edges {
+		if pl.stages[from] == nil {
+			errs = append(errs, fmt.Errorf("unknown stage %q is connected to other stages", from))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Sort the stages topologically, any stages left unsorted are in a cycle.
+	pl.order, pl.sources, pl.sinks = nil, nil, nil
+	for _, name := range pl.names {
+		if incoming[name] == 0 {
+			pl.sources = append(pl.sources, name)
+		}
+		if len(pl.edges[name]) == 0 {
+			pl.sinks = append(pl.sinks, name)
+		}
+	}
+	queue := append([]string{}, pl.sources...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		pl.order = append(pl.order, name)
+		for _, to := range pl.edges[name] {
+			incoming[to]--
+			if incoming[to] == 0 {
+				queue = append(queue, to)
+			}
+		}
+	}
+	if len(pl.order) < len(pl.names) {
+		cycled := []string{}
+		for _, name := range pl.names {
+			if incoming[name] > 0 {
+				cycled = append(cycled, name)
+			}
+		}
+		return fmt.Errorf("the pipeline has a cycle through stages %q", cycled)
+	}
+	return nil
+}
+
+// Submit sends the job to the only source stage that takes jobs of its type.
+func (pl *Pipeline) Submit(job any) error {
+	if !pl.built {
+		return errors.New("the pipeline isn't built")
+	}
+	accepting := []string{}
+	for _, name := range pl.sources {
+		if pl.stages[name].accepts(job) {
+			accepting = append(accepting, name)
+		}
+	}
+	switch len(accepting) {
+	case 0:
+		return fmt.Errorf("no source stage takes jobs of type %T", job)
+	case 1:
+		return pl.stages[accepting[0]].submitAny(job)
+	default:
+		return fmt.Errorf("source stages %q all take jobs of type %T, use SubmitTo", accepting, job)
+	}
+}
+
+// SubmitTo sends the job to the named source stage.
+func (pl *Pipeline) SubmitTo(name string, job any) error {
+	if !pl.built {
+		return errors.New("the pipeline isn't built")
+	}
+	for _, source := range pl.sources {
+		if source == name {
+			return pl.stages[name].submitAny(job)
+		}
+	}
+	return fmt.Errorf("stage %q isn't a source stage", name)
+}
+
+// Wait will block until no stage has waiting jobs, waiting on the stages in
+// topological order, so jobs sent further down are waited on too.
+func (pl *Pipeline) Wait() {
+	for _, name := range pl.order {
+		pl.stages[name].Wait()
+	}
+}
+
+// Close will close all the sink stages at once, which in turn close the stages
+// sending jobs to them, see `Pool.Close`. Returns the errors of the sinks.
+func (pl *Pipeline) Close(force ...bool) error {
+	closing := &sync.WaitGroup{}
+	errs := make([]error, len(pl.sinks))
+	for i, name := range pl.sinks {
+		closing.Add(1)
+		go func(i int, stage Stage) {
+			defer closing.Done()
+			if err := stage.Close(force...); err != nil {
+				errs[i] = fmt.Errorf("closing stage %q: %w", pl.sinks[i], err)
+			}
+		}(i, pl.stages[name])
+	}
+	closing.Wait()
+	return errors.Join(errs...)
+}
+
+// Stats returns the counters of all stages in topological order.
+func (pl *Pipeline) Stats() []StageStats {
+	stats := make([]StageStats, 0, len(pl.order))
+	for _, name := range pl.order {
+		stage := pl.stages[name]
+		stats = append(stats, StageStats{
+			Name:          name,
+			JobsCompleted: stage.JobsCompleted(),
+			JobsWaiting:   stage.JobsWaiting(),
+			JobsSucceeded: stage.JobsSucceeded(),
+		})
+	}
+	return stats
+}
+
+// accepts returns true if the job is of pool's input type.
+func (p *Pool[I, _]) accepts(job any) bool {
+	_, ok := job.(I)
+	return ok
+}
+
+// submitAny submits the job to the pool, if it's of pool's input type.
+func (p *Pool[I, _]) submitAny(job any) error {
+	typed, ok := job.(I)
+	if !ok {
+		return fmt.Errorf("pool %s doesn't take jobs of type %T", p.Name(), job)
+	}
+	return p.Submit(typed)
+}
+
+// canConnectTo returns a non-nil error if the pool's outputs can't be sent
+// as jobs to the parent.
+func (p *Pool[_, O]) canConnectTo(parent Stage) error {
+	if !p.producesOutputs() {
+		return errors.New("not producing outputs")
+	}
+	if p.outputHandler != nil {
+		return errors.New("outputs go to the output handler")
+	}
+	if p.IsConnected() {
+		return errors.New("a connector is already running")
+	}
+	if _, ok := parent.(PoolConnector[O]); !ok {
+		return fmt.Errorf("pool %s doesn't take jobs of type %v", parent.Name(), reflect.TypeFor[O]())
+	}
+	return nil
+}
+
+// connectTo connects the pool to the parents, broadcasting its outputs
+// if there is more than one.
+func (p *Pool[_, O]) connectTo(parents []Stage) error {
+	connectors := make([]PoolConnector[O], len(parents))
+	for i, parent := range parents {
+		connector, ok := parent.(PoolConnector[O])
+		if !ok {
+			return fmt.Errorf("pool %s doesn't take jobs of type %v", parent.Name(), reflect.TypeFor[O]())
+		}
+		connectors[i] = connector
+	}
+	return p.Broadcast(connectors...)
+}
@@ -1,7 +1,7 @@
 package komi
 
 import (
-	"os"
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,24 +21,40 @@ func NewWithSettings[I, O any](optionWork poolWork[I, O], settings *Settings) *P
 		jobsWaiting:         &atomic.Int64{},
 		jobsCompleted:       &atomic.Int64{},
 		jobsSucceeded:       &atomic.Int64{},
+		jobsRejected:        &atomic.Int64{},
+		jobsRetried:         &atomic.Int64{},
+		jobsDropped:         &atomic.Int64{},
 		tellChildrenToClose: make(chan Signal),
 		closedSignal:        make(chan Signal, 1),
-		closureRequest:      make(chan bool),
-		closureInternalWait: &sync.WaitGroup{},
-		log: log.NewWithOptions(os.Stderr, log.Options{
-			TimeFormat:      time.DateTime,
-			ReportTimestamp: true,
-			ReportCaller:    false,
-		}),
-		currentlyWaitingForJobs:      &atomic.Bool{},
-		noJobsCurrentlyWaitingSignal: make(chan Signal),
+		closureRequest:      make(chan closure[I]),
+		state:               &atomic.Int32{},
+		intake:              &sync.RWMutex{},
+		intakeStopSignal:    make(chan Signal),
+		children:            &children{},
+		laborersLock:        &sync.Mutex{},
+		outputHandlerLock:   &sync.Mutex{},
+		laborersWanted:      &atomic.Int64{},
+		laborersCount:       &atomic.Int64{},
+		laborersBusy:        &atomic.Int64{},
+		leftovers:           &leftovers[I]{},
+		laborerIDs:          &laborerIDs{},
+		discardOutcomes:     &atomic.Bool{},
+		connected:           &atomic.Bool{},
+		queueWaitTotal:      &atomic.Int64{},
+		queueWaitCount:      &atomic.Int64{},
+		queueWaitTimes:      newHistogram(),
+		executionTimes:      newHistogram(),
+		created:             time.Now(),
+		limiter:             &rateLimiter{},
+		timeThrottled:       &atomic.Int64{},
+		noJobsWaitingSignal: newBroadcaster(),
 	}
 
 	// Run the function to set the work performer for the pool.
 	optionWork(p)
 
 	// If work received and set is a nil function, then immediately panic.
-	if !p.hasWork() || p.workPerformer == nil {
+	if !p.hasWork() || (p.workPerformer == nil && !p.isWorkState()) {
 		panic("pool didn't receive any work")
 	}
 
@@ -48,9 +64,16 @@ func NewWithSettings[I, O any](optionWork poolWork[I, O], settings *Settings) *P
 	}
 	verifySettings(p.settings)
 
-	// Set the logging levels and options.
-	p.log.SetLevel(p.settings.LogLevel)
-	p.log.SetPrefix(p.settings.Name)
+	// Set the pool-level context, which is cancelled once the pool is closed.
+	p.ctx, p.cancel = context.WithCancel(p.settings.Context)
+
+	// Set the rate limit, if any.
+	if err := p.SetRateLimit(p.settings.RateLimit); err != nil {
+		panic(err)
+	}
+
+	// Set the logger, levels and options.
+	p.log, p.defaultLog = newLogger(p.settings)
 
 	// If usar has not provided a manual size setting, then set `size = laborers * ratio`.
 	if !p.settings.sizeOverride {
@@ -61,32 +84,71 @@ func NewWithSettings[I, O any](optionWork poolWork[I, O], settings *Settings) *P
 	p.log.Debug("Pool settings initialized")
 
 	// Allocate the channel with proper size.
-	p.inputs = make(chan I, p.settings.Size)
+	p.inputs = make(chan task[I], p.settings.Size)
+	p.ready = p.inputs
+
+	// If the pool is prioritized, jobs are only handed over to the scheduler,
+	// which keeps them in its priority queue of the proper size instead.
+	if p.settings.Prioritized {
+		if p.settings.Ordered {
+			panic("an ordered pool can't be prioritized")
+		}
+		p.inputs = make(chan task[I], 1)
+		p.ready = make(chan task[I])
+		p.scheduler = newScheduler[I](p.settings.Aging)
+	}
 
-	// If the function given produces outputs, also allocate the outputs channel.
-	if p.producesOutputs() {
+	// If the function given produces outputs, also allocate the outputs channel,
+	// unless they are consumed by the output handler.
+	if p.producesOutputs() && p.outputHandler == nil {
 		p.outputs = make(chan O, p.settings.Size)
 	}
 
-	// If the function given produces errors, also allocated the errors channel.
-	if p.producesErrors() {
+	// If the function given produces errors, also allocated the errors channel,
+	// unless they are consumed by the error handler.
+	if p.producesErrors() && p.errorHandler == nil {
 		p.errors = make(chan PoolError[I], p.settings.Size)
 	}
 
+	// If requested, allocate the dead letters channel for dropped jobs.
+	if p.settings.DeadLetters {
+		p.deadLetters = make(chan I, p.settings.Size)
+	}
+
+	// If the pool is ordered, number the jobs to release their outcomes in order.
+	if p.settings.Ordered {
+		p.sequencer = newSequencer(p.settings.ReorderWindow)
+	}
+
 	// Fire off all the laborers.
-	p.startLaborers()
+	if err := p.startLaborers(); err != nil {
+		panic(err)
+	}
+
+	// If the pool is prioritized, start scheduling jobs.
+	if p.scheduler != nil {
+		go p.schedule()
+	}
+
+	// If jobs can spill over, start moving them to the inputs channel.
+	if p.settings.OverflowPolicy == OverflowSpill {
+		p.overflow = &overflow[I]{spilled: make(chan Signal, 1), fed: make(chan Signal)}
+		go p.feedOverflow()
+	}
 
 	go p.closureRequestListener()
 
 	return p
 }
 
-// SetLevel the logging level of the pool.
+// SetLevel the logging level of the pool, if it logs with the default logger.
 func (p *Pool[_, _]) SetLevel(level log.Level) {
-	p.log.SetLevel(level)
+	if p.defaultLog != nil {
+		p.defaultLog.SetLevel(level)
+	}
 }
 
-// Debug enables the debug logging in the pool.
+// Debug enables the debug logging in the pool, if it logs with the default logger.
 func (p *Pool[_, _]) Debug() {
-	p.log.SetLevel(log.DebugLevel)
+	p.SetLevel(log.DebugLevel)
 }
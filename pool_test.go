@@ -1,8 +1,18 @@
 package komi
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +25,7 @@ func TestPoolCreationClosing(t *testing.T) {
 	})
 	assert.NotNil(t, simplePool, "simple pool")
 	simplePool.Close()
-	assert.Equal(t, true, simplePool.closed, "closed flag")
+	assert.Equal(t, StateClosed, simplePool.State(), "closed state")
 	assert.NotEmpty(t, simplePool.closedSignal, "closed signal channel")
 
 	simplePoolWithErrors := NewWithSettings(WorkSimpleWithErrors(squareSimpleWithErrors), &Settings{
@@ -38,6 +48,1230 @@ func TestPoolCreationClosing(t *testing.T) {
 	defer regularPoolWithErrors.Close()
 }
 
+func TestPoolContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan Signal)
+	pool := NewWithSettings(WorkCtx(func(ctx context.Context, v int) (int, error) {
+		started <- signal
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}), &Settings{
+		Laborers: 1,
+		Size:     1,
+		Name:     "Context Pool",
+		Context:  ctx,
+	})
+	errs, err := pool.Errors()
+	assert.Nil(t, err, "errors channel")
+
+	// One job is taken by the only laborer, the other one fills the queue.
+	assert.Nil(t, pool.Submit(1), "first submission")
+	<-started
+	assert.Nil(t, pool.Submit(2), "second submission")
+
+	// A submission with its own context should give up when it's done.
+	submitCtx, submitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer submitCancel()
+	assert.ErrorIs(t, pool.SubmitContext(submitCtx, 3), context.DeadlineExceeded, "submission deadline")
+
+	// Cancelling the pool's context should release the blocked submitter.
+	submitted := make(chan error)
+	go func() { submitted <- pool.Submit(4) }()
+	cancel()
+	assert.ErrorIs(t, <-submitted, context.Canceled, "pool cancellation")
+	assert.ErrorIs(t, (<-errs).Error, context.Canceled, "job's context")
+	assert.ErrorIs(t, pool.Submit(5), context.Canceled, "submission after cancellation")
+
+	pool.Close()
+	assert.True(t, pool.IsClosed(), "closed")
+}
+
+func TestPoolPanicRecovery(t *testing.T) {
+	pool := NewWithSettings(WorkWithErrors(func(v int) (int, error) {
+		if v == 0 {
+			panic("zero")
+		}
+		return squarReguralWithErrors(v)
+	}), &Settings{
+		Laborers:       1,
+		Name:           "Panicking Pool",
+		RestartOnPanic: true,
+	})
+	outputs, _ := pool.Outputs()
+	errs, _ := pool.Errors()
+
+	assert.Nil(t, pool.Submit(0), "panicking submission")
+	poolErr := <-errs
+	assert.ErrorIs(t, poolErr.Error, ErrJobPanicked, "panic error")
+	assert.Equal(t, "zero", poolErr.Panic, "panic value")
+	assert.NotEmpty(t, poolErr.Stack, "panic stack")
+	assert.Equal(t, 0, poolErr.Job, "panicking job")
+
+	// The restarted laborer should pick up the next job.
+	assert.Nil(t, pool.Submit(2), "regular submission")
+	assert.Equal(t, 4, <-outputs, "output after panic")
+
+	pool.Close()
+	assert.Equal(t, int64(2), pool.JobsCompleted(), "completed")
+	assert.Equal(t, int64(1), pool.JobsSucceeded(), "succeeded")
+
+	// By default, the laborer that recovered keeps working.
+	kept := NewWithSettings(WorkSimple(func(v int) {
+		if v == 0 {
+			panic("zero")
+		}
+	}), &Settings{
+		Laborers: 1,
+		Name:     "Recovering Pool",
+	})
+	assert.Nil(t, kept.Submit(0), "panicking submission")
+	assert.Nil(t, kept.Submit(2), "regular submission")
+	kept.Wait()
+	assert.Equal(t, 1, kept.Laborers(), "laborers after panic")
+	assert.Equal(t, int64(1), kept.JobsSucceeded(), "succeeded")
+	kept.Close()
+}
+
+func TestPoolRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	calls := &atomic.Int64{}
+	pool := NewWithSettings(WorkSimpleWithErrors(func(v int) error {
+		if v < 0 {
+			return errFatal
+		}
+		if calls.Add(1) < 3 {
+			return errFlaky
+		}
+		return nil
+	}), &Settings{
+		Laborers: 1,
+		Name:     "Retrying Pool",
+		Retry: &RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond,
+			Jitter:      0.5,
+			Retryable:   func(err error) bool { return !errors.Is(err, errFatal) },
+		},
+	})
+	errs, _ := pool.Errors()
+
+	// Succeeds on the third attempt.
+	assert.Nil(t, pool.Submit(1), "flaky submission")
+	// Fails right away, because the error isn't retryable.
+	assert.Nil(t, pool.Submit(-1), "fatal submission")
+	poolErr := <-errs
+	assert.ErrorIs(t, poolErr.Error, errFatal, "fatal error")
+	assert.Equal(t, 1, poolErr.Attempts, "fatal attempts")
+	assert.Empty(t, poolErr.PreviousErrors, "fatal previous errors")
+
+	// Runs out of attempts.
+	calls.Store(-10)
+	assert.Nil(t, pool.Submit(2), "exhausted submission")
+	poolErr = <-errs
+	assert.ErrorIs(t, poolErr.Error, errFlaky, "exhausted error")
+	assert.Equal(t, 3, poolErr.Attempts, "exhausted attempts")
+	assert.Equal(t, []error{errFlaky, errFlaky}, poolErr.PreviousErrors, "exhausted previous errors")
+
+	pool.Close()
+	assert.Equal(t, int64(3), pool.JobsCompleted(), "completed")
+	assert.Equal(t, int64(1), pool.JobsSucceeded(), "succeeded")
+}
+
+func TestPoolFanIn(t *testing.T) {
+	consumer := NewWithSettings(Work(squareRegular), &Settings{Name: "Consumer"})
+	producers := make([]*Pool[int, int], 3)
+	for i := range producers {
+		producers[i] = NewWithSettings(Work(squareRegular), &Settings{Name: "Producer"})
+		assert.Nil(t, producers[i].Connect(consumer), "connect")
+	}
+	outputs, _ := consumer.Outputs()
+	sum := make(chan int)
+	go func() {
+		total := 0
+		for range 10 * len(producers) {
+			total += <-outputs
+		}
+		sum <- total
+	}()
+
+	for _, producer := range producers {
+		for v := 1; v <= 10; v++ {
+			assert.Nil(t, producer.Submit(v), "submission")
+		}
+	}
+	assert.Equal(t, 3*25333, <-sum, "sum of fourth powers")
+
+	// Closing the consumer should close all the producers.
+	consumer.Close()
+	assert.True(t, consumer.IsClosed(), "consumer closed")
+	for _, producer := range producers {
+		assert.True(t, producer.IsClosed(), "producer closed")
+	}
+
+	// Outputs produced before connecting stay in the outputs channel.
+	consumer = NewWithSettings(Work(squareRegular), &Settings{Name: "Consumer"})
+	producer := NewWithSettings(Work(squareRegular), &Settings{Name: "Producer"})
+	assert.Nil(t, producer.Submit(2), "submission")
+	producer.Wait()
+	assert.NotNil(t, producer.Connect(consumer), "connect with outputs unread")
+	producerOutputs, _ := producer.Outputs()
+	assert.Equal(t, 4, <-producerOutputs, "output before connecting")
+	assert.Nil(t, producer.Connect(consumer), "connect")
+	assert.Nil(t, producer.Submit(3), "submission")
+	producer.Wait()
+	assert.Equal(t, int64(2), producer.JobsCompleted(), "completed")
+	assert.Equal(t, int64(0), producer.JobsWaiting(), "waiting")
+	outputs, _ = consumer.Outputs()
+	assert.Equal(t, 81, <-outputs, "output after connecting")
+	consumer.Close()
+}
+
+func TestPoolFanOut(t *testing.T) {
+	sumOutputs := func(pool *Pool[int, int], n int) chan int {
+		outputs, _ := pool.Outputs()
+		sum := make(chan int)
+		go func() {
+			total := 0
+			for range n {
+				total += <-outputs
+			}
+			sum <- total
+		}()
+		return sum
+	}
+
+	// Every output goes to both parents.
+	producer := NewWithSettings(Work(squareRegular), &Settings{Name: "Broadcaster"})
+	first := NewWithSettings(Work(squareRegular), &Settings{Name: "First"})
+	second := NewWithSettings(Work(squareRegular), &Settings{Name: "Second"})
+	assert.Nil(t, producer.Broadcast(first, second), "broadcast")
+	assert.NotNil(t, producer.Connect(first), "second connection")
+	firstSum, secondSum := sumOutputs(first, 10), sumOutputs(second, 10)
+	for v := 1; v <= 10; v++ {
+		assert.Nil(t, producer.Submit(v), "submission")
+	}
+	assert.Equal(t, 25333, <-firstSum, "first sum")
+	assert.Equal(t, 25333, <-secondSum, "second sum")
+
+	// The producer should close only after both parents have left.
+	first.Close()
+	assert.False(t, producer.IsClosed(), "producer closed with one parent")
+	second.Close()
+	assert.True(t, producer.IsClosed(), "producer closed with no parents")
+
+	// Odd outputs go to the first parent, even to the second.
+	producer = NewWithSettings(Work(squareRegular), &Settings{Name: "Router"})
+	odd := NewWithSettings(Work(squareRegular), &Settings{Name: "Odd"})
+	even := NewWithSettings(Work(squareRegular), &Settings{Name: "Even"})
+	assert.Nil(t, producer.Route(func(v int) int { return 1 - v%2 }, odd, even), "route")
+	oddSum, evenSum := sumOutputs(odd, 5), sumOutputs(even, 5)
+	for v := 1; v <= 10; v++ {
+		assert.Nil(t, producer.Submit(v), "submission")
+	}
+	assert.Equal(t, 1+81+625+2401+6561, <-oddSum, "odd sum")
+	assert.Equal(t, 25333-(1+81+625+2401+6561), <-evenSum, "even sum")
+	even.Close()
+	odd.Close()
+	assert.True(t, producer.IsClosed(), "router closed")
+}
+
+func TestPipeline(t *testing.T) {
+	square := NewWithSettings(Work(squareRegular), &Settings{Name: "Square"})
+	format := NewWithSettings(Work(strconv.Itoa), &Settings{Name: "Format"})
+	digits := NewWithSettings(Work(func(s string) int { return len(s) }), &Settings{Name: "Digits"})
+
+	// Type mismatches and cycles are caught when building.
+	err := NewPipeline().
+		Add("square", square).
+		Add("format", format).
+		Connect("format", "square").
+		Build()
+	assert.ErrorContains(t, err, "doesn't take jobs of type string", "type mismatch")
+	err = NewPipeline().
+		Add("square", square).
+		Add("again", square).
+		Connect("square", "again").
+		Connect("again", "square").
+		Build()
+	assert.ErrorContains(t, err, "cycle", "cycle")
+
+	pipeline := NewPipeline().
+		Add("square", square).
+		Add("format", format).
+		Add("digits", digits).
+		Connect("square", "format").
+		Connect("format", "digits")
+	assert.Nil(t, pipeline.Build(), "build")
+	assert.NotNil(t, pipeline.Submit("not a number"), "wrong job type")
+
+	outputs, _ := digits.Outputs()
+	total := make(chan int)
+	go func() {
+		// Closing discards unread outputs, so read all of them first.
+		sum := 0
+		for range 100 {
+			sum += <-outputs
+		}
+		total <- sum
+	}()
+	for v := 1; v <= 100; v++ {
+		assert.Nil(t, pipeline.Submit(v), "submission")
+	}
+	pipeline.Wait()
+	stats := pipeline.Stats()
+	assert.Equal(t, []string{"square", "format", "digits"}, []string{stats[0].Name, stats[1].Name, stats[2].Name}, "stages order")
+	for _, stage := range stats {
+		assert.Equal(t, int64(100), stage.JobsCompleted, "stage completed")
+		assert.Equal(t, int64(0), stage.JobsWaiting, "stage waiting")
+	}
+
+	// 1-3 squared have 1 digit, 4-9 have 2, 10-31 have 3, 32-99 have 4, 100 has 5.
+	assert.Equal(t, 3+6*2+22*3+68*4+5, <-total, "digits of squares")
+	pipeline.Close()
+	assert.True(t, square.IsClosed(), "source closed")
+}
+
+func TestPoolOrdered(t *testing.T) {
+	pool := NewWithSettings(WorkWithErrors(func(v int) (int, error) {
+		// Later jobs complete sooner.
+		time.Sleep(time.Duration(10-v%10) * time.Millisecond)
+		if v%3 == 0 {
+			return 0, errors.New("divisible by three")
+		}
+		return v, nil
+	}), &Settings{
+		Laborers:      8,
+		Name:          "Ordered Pool",
+		Ordered:       true,
+		ReorderWindow: 4,
+	})
+	outputs, _ := pool.Outputs()
+	errs, _ := pool.Errors()
+	released := make(chan [2][]int)
+	go func() {
+		outputsOrder, errorsOrder := []int{}, []int{}
+		for len(outputsOrder)+len(errorsOrder) < 30 {
+			select {
+			case v := <-outputs:
+				outputsOrder = append(outputsOrder, v)
+			case poolErr := <-errs:
+				errorsOrder = append(errorsOrder, poolErr.Job)
+			}
+		}
+		released <- [2][]int{outputsOrder, errorsOrder}
+	}()
+
+	expected := [2][]int{}
+	for v := 1; v <= 30; v++ {
+		assert.Nil(t, pool.Submit(v), "submission")
+		if v%3 == 0 {
+			expected[1] = append(expected[1], v)
+		} else {
+			expected[0] = append(expected[0], v)
+		}
+	}
+	assert.Equal(t, expected, <-released, "release order")
+	pool.Close()
+
+	// A submitter waiting for room doesn't block the others.
+	release := make(chan Signal)
+	full := NewWithSettings(Work(func(v int) int {
+		<-release
+		return v
+	}), &Settings{
+		Laborers: 1,
+		Size:     1,
+		Name:     "Full Ordered Pool",
+		Ordered:  true,
+	})
+	assert.Nil(t, full.Submit(1), "first submission")
+	assert.Eventually(t, func() bool { return len(full.inputs) == 0 }, time.Second, time.Millisecond, "job taken")
+	assert.Nil(t, full.Submit(2), "second submission")
+	submitted := make(chan error)
+	go func() { submitted <- full.Submit(3) }()
+	ok, err := full.TrySubmit(4)
+	assert.False(t, ok, "rejected submission")
+	assert.ErrorIs(t, err, ErrPoolFull, "rejected submission")
+	assert.ErrorIs(t, full.SubmitTimeout(5, 5*time.Millisecond), ErrPoolFull, "timed out submission")
+	close(release)
+	assert.Nil(t, <-submitted, "waiting submission")
+	fullOutputs, _ := full.Outputs()
+	for v := 1; v <= 3; v++ {
+		assert.Equal(t, v, <-fullOutputs, "release order")
+	}
+	full.Close()
+}
+
+func TestPoolJobTimeout(t *testing.T) {
+	hang := make(chan Signal)
+	defer close(hang)
+	pool := NewWithSettings(WorkSimpleWithErrors(func(v int) error {
+		if v < 0 {
+			<-hang
+		}
+		time.Sleep(time.Duration(v) * time.Millisecond)
+		return nil
+	}), &Settings{
+		Laborers:   1,
+		Name:       "Timing Out Pool",
+		JobTimeout: 20 * time.Millisecond,
+	})
+	errs, _ := pool.Errors()
+
+	// The hanging job is abandoned, so the only laborer can move on.
+	assert.Nil(t, pool.Submit(-1), "hanging submission")
+	assert.Nil(t, pool.Submit(1), "regular submission")
+	poolErr := <-errs
+	assert.ErrorIs(t, poolErr.Error, ErrJobTimeout, "timeout error")
+	assert.Equal(t, -1, poolErr.Job, "timed out job")
+	pool.Wait()
+	assert.Equal(t, int64(2), pool.JobsCompleted(), "completed")
+	assert.Equal(t, int64(1), pool.JobsSucceeded(), "succeeded")
+
+	// A submission's deadline overrides the job timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, pool.SubmitContext(ctx, 40), "overridden submission")
+	pool.Wait()
+	assert.Equal(t, int64(2), pool.JobsSucceeded(), "succeeded with override")
+
+	pool.Close()
+}
+
+func TestPoolResizing(t *testing.T) {
+	pool := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Laborers: 2,
+		Name:     "Resizing Pool",
+	})
+	assert.Equal(t, 2, pool.Laborers(), "initial laborers")
+	assert.Nil(t, pool.SetLaborers(5), "grow")
+	assert.Equal(t, 5, pool.Laborers(), "grown laborers")
+	assert.Nil(t, pool.SetLaborers(1), "shrink")
+	assert.Eventually(t, func() bool { return pool.Laborers() == 1 }, time.Second, time.Millisecond, "shrunk laborers")
+	assert.NotNil(t, pool.SetLaborers(0), "no laborers")
+	pool.Close()
+	assert.NotNil(t, pool.SetLaborers(2), "closed pool")
+
+	autoscaled := NewWithSettings(WorkSimple(func(v int) {
+		time.Sleep(5 * time.Millisecond)
+	}), &Settings{
+		Name: "Autoscaled Pool",
+		Size: 100,
+		Autoscale: &Autoscale{
+			MinLaborers: 1,
+			MaxLaborers: 8,
+			Interval:    5 * time.Millisecond,
+		},
+	})
+	assert.Equal(t, 1, autoscaled.Laborers(), "initial autoscaled laborers")
+	for v := range 100 {
+		assert.Nil(t, autoscaled.Submit(v), "submission")
+	}
+	assert.Eventually(t, func() bool { return autoscaled.Laborers() == 8 }, time.Second, time.Millisecond, "scaled up")
+	autoscaled.Wait()
+	assert.Eventually(t, func() bool { return autoscaled.Laborers() == 1 }, time.Second, time.Millisecond, "scaled down")
+	autoscaled.Close()
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	pool := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Laborers:  4,
+		Name:      "Rate Limited Pool",
+		RateLimit: &RateLimit{Jobs: 100, Interval: time.Second, Burst: 1},
+	})
+	start := time.Now()
+	for v := range 6 {
+		assert.Nil(t, pool.Submit(v), "submission")
+	}
+	pool.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond, "limited duration")
+	assert.Greater(t, pool.TimeThrottled(), time.Duration(0), "time throttled")
+
+	// Removing the limit lets jobs go through right away.
+	assert.Nil(t, pool.SetRateLimit(nil), "remove limit")
+	throttled := pool.TimeThrottled()
+	for v := range 100 {
+		assert.Nil(t, pool.Submit(v), "submission")
+	}
+	pool.Wait()
+	assert.Equal(t, throttled, pool.TimeThrottled(), "no more throttling")
+	assert.NotNil(t, pool.SetRateLimit(&RateLimit{}), "invalid limit")
+	pool.Close()
+
+	// A fresh pool starts the whole burst right away.
+	bursty := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Laborers:  4,
+		Name:      "Bursty Pool",
+		RateLimit: &RateLimit{Jobs: 1, Interval: 10 * time.Second, Burst: 5},
+	})
+	start = time.Now()
+	for v := range 5 {
+		assert.Nil(t, bursty.Submit(v), "submission")
+	}
+	bursty.Wait()
+	assert.Less(t, time.Since(start), time.Second, "burst duration")
+	assert.Equal(t, time.Duration(0), bursty.TimeThrottled(), "no throttling in burst")
+	bursty.Close()
+}
+
+func TestPoolTrySubmit(t *testing.T) {
+	release := make(chan Signal)
+	pool := NewWithSettings(WorkSimple(func(v int) { <-release }), &Settings{
+		Laborers: 1,
+		Size:     1,
+		Name:     "Full Pool",
+	})
+
+	// The first job is taken by the only laborer, the second fills the queue.
+	ok, err := pool.TrySubmit(1)
+	assert.True(t, ok, "first submission")
+	assert.Nil(t, err, "first submission")
+	assert.Eventually(t, func() bool { return len(pool.inputs) == 0 }, time.Second, time.Millisecond, "job taken")
+	assert.Nil(t, pool.SubmitTimeout(2, time.Millisecond), "second submission")
+
+	ok, err = pool.TrySubmit(3)
+	assert.False(t, ok, "rejected submission")
+	assert.ErrorIs(t, err, ErrPoolFull, "rejected submission")
+	assert.ErrorIs(t, pool.SubmitTimeout(4, 5*time.Millisecond), ErrPoolFull, "timed out submission")
+	assert.Equal(t, int64(2), pool.JobsRejected(), "rejected")
+
+	close(release)
+	pool.Wait()
+	assert.Equal(t, int64(2), pool.JobsCompleted(), "completed")
+	pool.Close()
+}
+
+func TestPoolOverflow(t *testing.T) {
+	// Drop the oldest waiting jobs, handing them over to the drop handler.
+	release := make(chan Signal)
+	dropped := []int{}
+	dropping := NewWithSettings(WorkSimple(func(v int) { <-release }).OnDrop(func(v int) {
+		dropped = append(dropped, v)
+	}), &Settings{
+		Laborers:       1,
+		Size:           2,
+		Name:           "Dropping Pool",
+		OverflowPolicy: OverflowDropOldest,
+	})
+	assert.Nil(t, dropping.Submit(0), "taken job")
+	assert.Eventually(t, func() bool { return len(dropping.inputs) == 0 }, time.Second, time.Millisecond, "job taken")
+	for i := 1; i <= 4; i++ {
+		assert.Nil(t, dropping.Submit(i), "dropping submission")
+	}
+	assert.Equal(t, []int{1, 2}, dropped, "dropped jobs")
+	assert.Equal(t, int64(2), dropping.JobsDropped(), "dropped")
+	close(release)
+	dropping.Wait()
+	assert.Equal(t, int64(3), dropping.JobsCompleted(), "completed")
+	dropping.Close()
+
+	// Reject the newest jobs, sending them to dead letters.
+	release = make(chan Signal)
+	rejecting := NewWithSettings(WorkSimple(func(v int) { <-release }), &Settings{
+		Laborers:       1,
+		Size:           1,
+		Name:           "Rejecting Pool",
+		OverflowPolicy: OverflowRejectNewest,
+		DeadLetters:    true,
+	})
+	deadLetters, err := rejecting.DeadLetters()
+	assert.Nil(t, err, "dead letters")
+	assert.Nil(t, rejecting.Submit(0), "taken job")
+	assert.Eventually(t, func() bool { return len(rejecting.inputs) == 0 }, time.Second, time.Millisecond, "job taken")
+	assert.Nil(t, rejecting.Submit(1), "queued job")
+	assert.ErrorIs(t, rejecting.Submit(2), ErrPoolFull, "rejected job")
+	assert.Equal(t, 2, <-deadLetters, "dead letter")
+	close(release)
+	rejecting.Wait()
+	rejecting.Close()
+
+	// Spill the jobs over, performing all of them in submission order.
+	release = make(chan Signal)
+	performed := []int{}
+	spilling := NewWithSettings(WorkSimple(func(v int) {
+		<-release
+		performed = append(performed, v)
+	}), &Settings{
+		Laborers:       1,
+		Size:           1,
+		Name:           "Spilling Pool",
+		OverflowPolicy: OverflowSpill,
+	})
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, spilling.Submit(i), "spilling submission")
+	}
+	assert.Equal(t, int64(10), spilling.JobsWaiting(), "waiting")
+	close(release)
+	spilling.Wait()
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, performed, "performed jobs")
+	assert.Equal(t, int64(0), spilling.JobsDropped(), "dropped")
+	spilling.Close()
+}
+
+func TestPoolPriority(t *testing.T) {
+	release := make(chan Signal)
+	performed := []int{}
+	pool := NewWithSettings(WorkSimple(func(v int) {
+		<-release
+		performed = append(performed, v)
+	}), &Settings{
+		Laborers:    1,
+		Size:        10,
+		Name:        "Prioritized Pool",
+		Prioritized: true,
+		Aging:       time.Hour,
+	})
+	unprioritized := New(WorkSimple(squareSimple))
+	assert.NotNil(t, unprioritized.SubmitWithPriority(0, 0), "unprioritized submission")
+	unprioritized.Close()
+
+	// The first job is taken by the only laborer, the rest wait by priorities.
+	assert.Nil(t, pool.Submit(0), "taken job")
+	assert.Eventually(t, func() bool { return pool.laborersBusy.Load() == 1 }, time.Second, time.Millisecond, "job taken")
+	for i, priority := range []int{1, 3, 2, 3, 1} {
+		assert.Nil(t, pool.SubmitWithPriority(i+1, priority), "prioritized submission")
+	}
+	assert.Eventually(t, func() bool { return len(pool.JobsWaitingByPriority()) == 4 }, time.Second, time.Millisecond, "queued jobs")
+	assert.Equal(t, map[int]int64{0: 1, 1: 2, 2: 1, 3: 2}, pool.JobsWaitingByPriority(), "waiting by priority")
+
+	close(release)
+	pool.Wait()
+	assert.Equal(t, []int{0, 2, 4, 3, 1, 5}, performed, "performed jobs")
+	assert.Empty(t, pool.JobsWaitingByPriority(), "waiting by priority")
+	pool.Close()
+
+	// Jobs waiting long enough overtake the ones with higher priorities.
+	release = make(chan Signal)
+	performed = []int{}
+	aging := NewWithSettings(WorkSimple(func(v int) {
+		<-release
+		performed = append(performed, v)
+	}), &Settings{
+		Laborers:    1,
+		Name:        "Aging Pool",
+		Prioritized: true,
+		Aging:       10 * time.Millisecond,
+	})
+	assert.Nil(t, aging.Submit(0), "taken job")
+	assert.Eventually(t, func() bool { return aging.laborersBusy.Load() == 1 }, time.Second, time.Millisecond, "job taken")
+	assert.Nil(t, aging.SubmitWithPriority(1, 0), "low priority submission")
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, aging.SubmitWithPriority(2, 2), "high priority submission")
+	close(release)
+	aging.Wait()
+	assert.Equal(t, []int{0, 1, 2}, performed, "performed jobs")
+	aging.Close()
+}
+
+func TestPoolBatch(t *testing.T) {
+	batches := make(chan []int, 10)
+	pool := NewWithSettings(WorkBatch(func(jobs []int) ([]int, error) {
+		batches <- jobs
+		outs := make([]int, len(jobs))
+		errs := make(BatchErrors, len(jobs))
+		for i, v := range jobs {
+			outs[i], errs[i] = squarReguralWithErrors(v)
+		}
+		return outs, errs
+	}), &Settings{
+		Laborers:    1,
+		Size:        10,
+		Name:        "Batched Pool",
+		BatchSize:   4,
+		BatchLinger: time.Second,
+	})
+	outputs, err := pool.Outputs()
+	assert.Nil(t, err, "outputs channel")
+	errs, err := pool.Errors()
+	assert.Nil(t, err, "errors channel")
+
+	// Full batches go right away, the last one waits for the linger time.
+	assert.Nil(t, pool.SubmitBatch([]int{1, 2, 3, 4, 5, 6, 7, 8}), "batch submission")
+	assert.Equal(t, []int{1, 2, 3, 4}, <-batches, "first batch")
+	assert.Equal(t, []int{5, 6, 7, 8}, <-batches, "second batch")
+	for v := 1; v <= 8; v++ {
+		assert.Equal(t, v*v, <-outputs, "output")
+	}
+
+	// Only the failed jobs of a batch are reported.
+	assert.Nil(t, pool.SubmitBatch([]int{-1, 2}), "batch submission")
+	assert.Equal(t, []int{-1, 2}, <-batches, "lingered batch")
+	assert.Equal(t, -1, (<-errs).Job, "failed job")
+	assert.Equal(t, 4, <-outputs, "output")
+	pool.Wait()
+	assert.Equal(t, int64(9), pool.JobsSucceeded(), "succeeded")
+	pool.Close()
+}
+
+func TestPoolErrorHandler(t *testing.T) {
+	failed := &atomic.Int64{}
+	pool := NewWithSettings(WorkSimpleWithErrors(squareSimpleWithErrors).OnError(func(poolErr PoolError[int]) {
+		failed.Add(1)
+	}), &Settings{
+		Laborers: 2,
+		Size:     1,
+		Name:     "Error Handler Pool",
+	})
+	_, err := pool.Errors()
+	assert.NotNil(t, err, "errors channel")
+
+	// Nobody reads the errors, yet the pool doesn't get stuck.
+	for v := range 10 {
+		assert.Nil(t, pool.Submit(-v), "submission")
+	}
+	pool.Wait()
+	assert.Equal(t, int64(10), failed.Load(), "handled errors")
+	assert.Equal(t, int64(0), pool.JobsSucceeded(), "succeeded")
+	pool.Close()
+}
+
+func TestPoolOutputHandler(t *testing.T) {
+	sum := 0
+	pool := NewWithSettings(Work(squareRegular).OnOutput(func(v int) {
+		sum += v
+	}), &Settings{
+		Laborers: 4,
+		Size:     1,
+		Name:     "Output Handler Pool",
+	})
+	_, err := pool.Outputs()
+	assert.NotNil(t, err, "outputs channel")
+	parent := New(Work(squareRegular))
+	assert.NotNil(t, pool.Connect(parent), "connection")
+	parent.Close()
+
+	// Nobody reads the outputs, yet the pool doesn't get stuck.
+	for v := 1; v <= 10; v++ {
+		assert.Nil(t, pool.Submit(v), "submission")
+	}
+	pool.Wait()
+	assert.Equal(t, 385, sum, "handled outputs")
+	pool.Close()
+
+	concurrent := &atomic.Int64{}
+	concurrentPool := NewWithSettings(Work(squareRegular).OnOutput(func(v int) {
+		concurrent.Add(int64(v))
+	}), &Settings{
+		Laborers:          4,
+		Name:              "Concurrent Output Handler Pool",
+		ConcurrentOutputs: true,
+	})
+	for v := 1; v <= 10; v++ {
+		assert.Nil(t, concurrentPool.Submit(v), "submission")
+	}
+	concurrentPool.Wait()
+	assert.Equal(t, int64(385), concurrent.Load(), "handled outputs")
+	concurrentPool.Close()
+}
+
+func TestMap(t *testing.T) {
+	items := []int{3, -1, 2, 0, 1}
+	outputs, errs := Map(context.Background(), items, squarReguralWithErrors, &Settings{Laborers: 3})
+	assert.Equal(t, []int{9, 0, 4, 0, 1}, outputs, "outputs")
+	assert.Len(t, errs, 2, "errors")
+	assert.Equal(t, []int{-1, 0}, []int{errs[0].Job, errs[1].Job}, "failed jobs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs = ForEach(ctx, items, squareSimpleWithErrors, nil)
+	assert.Len(t, errs, len(items), "cancelled errors")
+	for _, poolErr := range errs {
+		assert.ErrorIs(t, poolErr.Error, context.Canceled, "cancelled error")
+	}
+}
+
+func TestPoolIterators(t *testing.T) {
+	pool := NewWithSettings(WorkWithErrors(squarReguralWithErrors), &Settings{
+		Laborers: 2,
+		Size:     10,
+		Name:     "Iterated Pool",
+	})
+	assert.Nil(t, pool.SubmitAll(slices.Values([]int{1, 2, -3, 4})), "submission")
+	outputs, failures := []int{}, 0
+	for output, err := range pool.Results() {
+		if err != nil {
+			failures++
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+	slices.Sort(outputs)
+	assert.Equal(t, []int{1, 4, 16}, outputs, "outputs")
+	assert.Equal(t, 1, failures, "failures")
+	pool.Close()
+
+	// Streaming can stop early, closing the pool.
+	streamed := 0
+	for output, err := range Stream(slices.Values([]int{1, 2, 3, 4, 5, 6}), squarReguralWithErrors, nil) {
+		assert.Nil(t, err, "streamed error")
+		assert.Positive(t, output, "streamed output")
+		if streamed++; streamed == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, streamed, "streamed outputs")
+}
+
+func TestPoolLogger(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	pool := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Name:   "Logged Pool",
+		Logger: logger,
+	})
+	pool.Close()
+	assert.Contains(t, logs.String(), `"msg":"Pool is closed"`, "logged closure")
+	assert.Contains(t, logs.String(), `"pool":"Logged Pool"`, "pool attribute")
+
+	handled := &bytes.Buffer{}
+	handler := slog.NewTextHandler(handled, &slog.HandlerOptions{Level: slog.LevelDebug})
+	handledPool := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Name:       "Handled Pool",
+		LogHandler: handler,
+	})
+	handledPool.Close()
+	assert.Contains(t, handled.String(), `msg="Pool is closed"`, "logged closure")
+	assert.Contains(t, handled.String(), `pool="Handled Pool"`, "pool attribute")
+
+	silent := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Name:           "Silent Pool",
+		Debug:          true,
+		DisableLogging: true,
+	})
+	silent.Debug()
+	assert.False(t, silent.log.Enabled(context.Background(), slog.LevelError), "disabled logging")
+	silent.Close()
+}
+
+func TestPoolStats(t *testing.T) {
+	pool := NewWithSettings(WorkWithErrors(squarReguralWithErrors).OnOutput(func(int) {}).OnError(func(PoolError[int]) {}), &Settings{
+		Laborers: 2,
+		Name:     "Stats \"Pool\"",
+		Retry:    &RetryPolicy{MaxAttempts: 2},
+	})
+	for v := range 10 {
+		assert.Nil(t, pool.Submit(v), "submission")
+	}
+	pool.Wait()
+	stats := pool.Stats()
+	assert.Equal(t, int64(10), stats.JobsCompleted, "completed")
+	assert.Equal(t, int64(1), stats.JobsFailed, "failed")
+	assert.Equal(t, int64(1), stats.JobsRetried, "retried")
+	assert.Equal(t, int64(0), stats.QueueDepth, "queue depth")
+	assert.Equal(t, int64(2), stats.LaborersIdle, "idle laborers")
+	assert.Equal(t, int64(10), stats.QueueWait.Count, "queue wait count")
+	assert.Equal(t, int64(10), stats.Execution.Buckets[len(stats.Execution.Buckets)-1].Count, "execution count")
+
+	recorder := httptest.NewRecorder()
+	NewExporter(pool).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	metrics := recorder.Body.String()
+	assert.Contains(t, metrics, "# TYPE komi_jobs_completed_total counter\n", "metric type")
+	assert.Contains(t, metrics, `komi_jobs_completed_total{pool="Stats \"Pool\""} 10`, "metric")
+	assert.Contains(t, metrics, `komi_execution_seconds_bucket{pool="Stats \"Pool\"",le="+Inf"} 10`, "histogram")
+	pool.Close()
+}
+
+func TestPoolLifecycle(t *testing.T) {
+	pool := NewWithSettings(WorkSimple(squareSimple), &Settings{
+		Laborers: 2,
+		Size:     1,
+		Name:     "Lifecycle Pool",
+	})
+	assert.Equal(t, StateRunning, pool.State(), "running state")
+
+	// Submissions racing the closure never panic, they're either taken or refused.
+	submitters := &sync.WaitGroup{}
+	for range 8 {
+		submitters.Add(1)
+		go func() {
+			defer submitters.Done()
+			for v := 0; ; v++ {
+				if err := pool.Submit(v); err != nil {
+					assert.True(t, errors.Is(err, ErrPoolClosed) || errors.Is(err, ErrPoolDraining), "refused submission")
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, pool.Close(), "closure")
+	submitters.Wait()
+	assert.Equal(t, StateClosed, pool.State(), "closed state")
+	assert.ErrorIs(t, pool.Submit(0), ErrPoolClosed, "closed submission")
+	assert.ErrorIs(t, pool.Close(), ErrPoolClosed, "second closure")
+	_, err := pool.Outputs()
+	assert.ErrorIs(t, err, ErrPoolClosed, "closed outputs")
+
+	// Only the parent can close a connected pool.
+	child := New(Work(squareRegular))
+	parent := New(WorkSimple(squareSimple))
+	assert.Nil(t, child.Connect(parent), "connection")
+	assert.NotNil(t, child.Close(), "child closure")
+	assert.Nil(t, parent.Close(), "parent closure")
+	assert.True(t, child.IsClosed(), "child closed")
+}
+
+func TestPoolShutdown(t *testing.T) {
+	// In-work jobs finish, while the queued ones are returned in order.
+	release := make(chan Signal)
+	pool := NewWithSettings(WorkSimpleWithErrors(func(v int) error {
+		<-release
+		if v == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	}), &Settings{
+		Laborers: 2,
+		Size:     10,
+		Name:     "Shutdown Pool",
+	})
+	for v := range 10 {
+		assert.Nil(t, pool.Submit(v), "submission")
+	}
+	for pool.laborersBusy.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	errs, err := pool.Errors()
+	assert.Nil(t, err, "errors")
+	go func() {
+		<-pool.laborersStopSignal
+		close(release)
+	}()
+	report, err := pool.Shutdown(context.Background())
+	assert.Nil(t, err, "shutdown")
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7, 8, 9}, report.Unprocessed, "unprocessed jobs")
+	assert.EqualValues(t, 2, report.Completed, "completed jobs")
+	assert.EqualValues(t, 1, report.Failed, "failed jobs")
+	assert.EqualValues(t, 0, report.Abandoned, "abandoned jobs")
+	assert.Len(t, take(errs), 1, "errors left to read")
+	assert.EqualValues(t, 0, pool.JobsWaiting(), "waiting jobs")
+	assert.Equal(t, StateClosed, pool.State(), "closed state")
+	_, err = pool.Shutdown(context.Background())
+	assert.ErrorIs(t, err, ErrPoolClosed, "second shutdown")
+
+	// In-work jobs are abandoned after the deadline.
+	stuck := NewWithSettings(WorkCtx(func(ctx context.Context, v int) (int, error) {
+		<-ctx.Done()
+		return v, ctx.Err()
+	}), &Settings{
+		Laborers: 1,
+		Size:     10,
+		Name:     "Stuck Pool",
+	})
+	for v := range 3 {
+		assert.Nil(t, stuck.Submit(v), "submission")
+	}
+	for stuck.laborersBusy.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err = stuck.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "shutdown deadline")
+	assert.Equal(t, []int{1, 2}, report.Unprocessed, "unprocessed jobs")
+	assert.EqualValues(t, 1, report.Abandoned, "abandoned jobs")
+	assert.Equal(t, StateClosed, stuck.State(), "closed state")
+
+	// Children finish their queued jobs before the parent shuts down.
+	parent := NewWithSettings(WorkSimple(func(int) {}), &Settings{
+		Laborers: 2,
+		Name:     "Shutdown Parent Pool",
+	})
+	child := NewWithSettings(Work(func(v int) int {
+		time.Sleep(time.Millisecond)
+		return v
+	}), &Settings{
+		Laborers: 1,
+		Size:     20,
+		Name:     "Shutdown Child Pool",
+	})
+	assert.Nil(t, child.Connect(parent), "connection")
+	for v := range 20 {
+		assert.Nil(t, child.Submit(v), "submission")
+	}
+	report, err = parent.Shutdown(context.Background())
+	assert.Nil(t, err, "shutdown with children")
+	assert.EqualValues(t, 20, report.Completed+int64(len(report.Unprocessed)), "jobs of the children")
+	assert.EqualValues(t, 0, report.ChildrenDiscarded, "discarded jobs of the children")
+	assert.EqualValues(t, 20, child.JobsCompleted(), "completed jobs of the child")
+	assert.True(t, child.IsClosed(), "closed child")
+
+	// Children's jobs left after the deadline are reported.
+	parent = NewWithSettings(WorkSimple(func(int) {}), &Settings{
+		Laborers: 1,
+		Name:     "Shutdown Parent Pool",
+	})
+	child = NewWithSettings(WorkCtx(func(ctx context.Context, v int) (int, error) {
+		<-ctx.Done()
+		return v, ctx.Err()
+	}), &Settings{
+		Laborers: 1,
+		Size:     10,
+		Name:     "Shutdown Stuck Child Pool",
+	})
+	assert.Nil(t, child.Connect(parent), "connection")
+	for v := range 5 {
+		assert.Nil(t, child.Submit(v), "submission")
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err = parent.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "shutdown deadline with children")
+	assert.EqualValues(t, 5, report.ChildrenDiscarded, "discarded jobs of the children")
+	assert.Equal(t, StateClosed, parent.State(), "closed state")
+}
+
+func TestPoolFutures(t *testing.T) {
+	pool := NewWithSettings(WorkWithErrors(squarReguralWithErrors), &Settings{
+		Laborers: 2,
+		Name:     "Futures Pool",
+	})
+	outputs, _ := pool.Outputs()
+	errs, _ := pool.Errors()
+
+	// Futures coexist with the outputs of the regular submissions.
+	future, err := pool.SubmitFuture(2)
+	assert.Nil(t, err, "future submission")
+	assert.Nil(t, pool.Submit(3), "regular submission")
+	output, err := future.Get(context.Background())
+	assert.Nil(t, err, "future error")
+	assert.Equal(t, 4, output, "future output")
+	assert.Equal(t, 9, <-outputs, "regular output")
+	<-future.Done()
+
+	// Failures are only given to the future.
+	failing, err := pool.SubmitFuture(-1)
+	assert.Nil(t, err, "failing submission")
+	_, err = failing.Get(context.Background())
+	assert.NotNil(t, err, "future error")
+	pool.Wait()
+	assert.Len(t, errs, 0, "no errors sent")
+
+	// Chained futures pass on the outputs and the errors.
+	chained, _ := pool.SubmitFuture(3)
+	output, err = chained.Then(func(v int) (int, error) { return v + 1, nil }).
+		Then(func(v int) (int, error) { return v * 2, nil }).Get(context.Background())
+	assert.Nil(t, err, "chained error")
+	assert.Equal(t, 20, output, "chained output")
+	failing, _ = pool.SubmitFuture(0)
+	_, err = failing.Then(func(v int) (int, error) { return v + 1, nil }).Get(context.Background())
+	assert.NotNil(t, err, "chained failure")
+
+	output, err = pool.Do(context.Background(), 5)
+	assert.Nil(t, err, "do error")
+	assert.Equal(t, 25, output, "do output")
+	pool.Close()
+	_, err = pool.Do(context.Background(), 5)
+	assert.ErrorIs(t, err, ErrPoolClosed, "closed do")
+
+	// Futures of the jobs discarded by the closure don't hang.
+	release := make(chan Signal)
+	blocked := NewWithSettings(Work(func(v int) int {
+		<-release
+		return v
+	}), &Settings{
+		Laborers: 1,
+		Name:     "Blocked Futures Pool",
+	})
+	inWork, _ := blocked.SubmitFuture(1)
+	assert.Eventually(t, func() bool { return blocked.laborersBusy.Load() == 1 }, time.Second, time.Millisecond, "job taken")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = blocked.Do(ctx, 2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "do deadline")
+	queued, _ := blocked.SubmitFuture(3)
+	go func() {
+		<-blocked.laborersStopSignal
+		close(release)
+	}()
+	blocked.Close()
+	output, err = inWork.Get(context.Background())
+	assert.Nil(t, err, "in-work future error")
+	assert.Equal(t, 1, output, "in-work future output")
+	_, err = queued.Get(context.Background())
+	assert.ErrorIs(t, err, ErrJobDiscarded, "discarded future")
+}
+
+func TestPoolWorkWithState(t *testing.T) {
+	type client struct {
+		laborerID int
+		jobs      int
+	}
+	initialized, tornDown := &atomic.Int64{}, &atomic.Int64{}
+	pool := NewWithSettings(WorkWithState(func(laborerID int) (*client, error) {
+		initialized.Add(1)
+		return &client{laborerID: laborerID}, nil
+	}, func(c *client, v int) (int, error) {
+		c.jobs++
+		return c.laborerID, nil
+	}, func(c *client) {
+		tornDown.Add(1)
+	}), &Settings{
+		Laborers: 4,
+		Name:     "Stateful Pool",
+	})
+	assert.EqualValues(t, 4, initialized.Load(), "initialized laborers")
+	outputs, _ := pool.Outputs()
+	go func() {
+		for v := range 100 {
+			assert.Nil(t, pool.Submit(v), "submission")
+		}
+	}()
+	for range 100 {
+		laborerID := <-outputs
+		assert.True(t, laborerID >= 0 && laborerID < 4, "laborer ID")
+	}
+
+	// Shrinking tears down the state of the retired laborers.
+	assert.Nil(t, pool.SetLaborers(2), "shrinking")
+	assert.Eventually(t, func() bool { return tornDown.Load() == 2 }, time.Second, time.Millisecond, "retired laborers")
+	assert.Nil(t, pool.SetLaborers(3), "growing")
+	assert.Eventually(t, func() bool { return initialized.Load() == 5 }, time.Second, time.Millisecond, "new laborer")
+	pool.Close()
+	assert.EqualValues(t, 5, tornDown.Load(), "torn down laborers")
+
+	// Init failures are surfaced at construction, after tearing down the others.
+	tornDown.Store(0)
+	assert.PanicsWithError(t, "initializing laborer 2: no connection", func() {
+		NewWithSettings(WorkWithState(func(laborerID int) (int, error) {
+			if laborerID == 2 {
+				return 0, errors.New("no connection")
+			}
+			return laborerID, nil
+		}, func(int, int) (int, error) {
+			return 0, nil
+		}, func(int) {
+			tornDown.Add(1)
+		}), &Settings{
+			Laborers: 4,
+			Name:     "Disconnected Pool",
+		})
+	}, "init failure")
+	assert.EqualValues(t, 2, tornDown.Load(), "torn down laborers")
+
+	// Laborers abandoning a job are replaced, and their state is torn
+	// down only once the abandoned job returns.
+	initialized.Store(0)
+	tornDown.Store(0)
+	release := make(chan Signal)
+	slow := NewWithSettings(WorkWithState(func(laborerID int) (*client, error) {
+		initialized.Add(1)
+		return &client{laborerID: laborerID}, nil
+	}, func(c *client, v int) (int, error) {
+		if v == 0 {
+			<-release
+		}
+		c.jobs++
+		return c.laborerID, nil
+	}, func(c *client) {
+		c.jobs = -1
+		tornDown.Add(1)
+	}), &Settings{
+		Laborers:   1,
+		JobTimeout: 10 * time.Millisecond,
+		Name:       "Abandoning Stateful Pool",
+	})
+	outputs, _ = slow.Outputs()
+	errs, _ := slow.Errors()
+	assert.Nil(t, slow.Submit(0), "submission")
+	assert.Nil(t, slow.Submit(1), "submission")
+	assert.ErrorIs(t, (<-errs).Error, ErrJobTimeout, "abandoned job")
+	assert.Equal(t, 1, <-outputs, "replacement laborer ID")
+	assert.EqualValues(t, 2, initialized.Load(), "initialized laborers")
+	assert.EqualValues(t, 0, tornDown.Load(), "torn down laborers")
+	close(release)
+	assert.Eventually(t, func() bool { return tornDown.Load() == 1 }, time.Second, time.Millisecond, "abandoned laborer")
+	slow.Close()
+	assert.EqualValues(t, 2, tornDown.Load(), "torn down laborers")
+}
+
+func TestPoolLaborerRecycling(t *testing.T) {
+	type worker struct {
+		jobs int
+	}
+	initialized, tornDown := &atomic.Int64{}, &atomic.Int64{}
+	pool := NewWithSettings(WorkWithState(func(int) (*worker, error) {
+		initialized.Add(1)
+		return &worker{}, nil
+	}, func(w *worker, v int) (int, error) {
+		w.jobs++
+		return w.jobs, nil
+	}, func(*worker) {
+		tornDown.Add(1)
+	}), &Settings{
+		Laborers:       2,
+		Name:           "Recycled Pool",
+		LaborerMaxJobs: 3,
+	})
+	outputs, _ := pool.Outputs()
+	go func() {
+		for v := range 30 {
+			assert.Nil(t, pool.Submit(v), "submission")
+		}
+	}()
+	for range 30 {
+		assert.LessOrEqual(t, <-outputs, 3, "jobs per laborer")
+	}
+	assert.Eventually(t, func() bool { return pool.JobsCompleted() == 30 }, time.Second, time.Millisecond, "completed")
+	assert.GreaterOrEqual(t, initialized.Load(), int64(10), "recycled laborers")
+	assert.Eventually(t, func() bool { return pool.Laborers() == 2 }, time.Second, time.Millisecond, "laborers")
+	pool.Close()
+	assert.Equal(t, initialized.Load(), tornDown.Load(), "torn down laborers")
+
+	// Idle laborers are recycled once they're old enough.
+	births := &atomic.Int64{}
+	aged := NewWithSettings(WorkWithState(func(int) (int, error) {
+		births.Add(1)
+		return 0, nil
+	}, func(_ int, v int) (int, error) {
+		return v, nil
+	}, nil), &Settings{
+		Laborers:      2,
+		Name:          "Aged Pool",
+		LaborerMaxAge: 5 * time.Millisecond,
+	})
+	assert.Eventually(t, func() bool { return births.Load() > 4 }, time.Second, time.Millisecond, "recycled laborers")
+	assert.Eventually(t, func() bool { return aged.Laborers() == 2 }, time.Second, time.Millisecond, "laborers")
+	aged.Close()
+
+	// Laborers failing to be replaced keep working.
+	births.Store(0)
+	failing := NewWithSettings(WorkWithState(func(int) (int, error) {
+		if births.Add(1) > 2 {
+			return 0, errors.New("no connection")
+		}
+		return 0, nil
+	}, func(_ int, v int) (int, error) {
+		return v, nil
+	}, nil), &Settings{
+		Laborers:       2,
+		Size:           10,
+		Name:           "Failing Recycled Pool",
+		LaborerMaxJobs: 1,
+	})
+	var initErrs atomic.Int64
+	go func() {
+		errs, _ := failing.Errors()
+		for err := range errs {
+			assert.ErrorIs(t, err.Error, ErrLaborerInit, "init failure")
+			initErrs.Add(1)
+		}
+	}()
+	outputs, _ = failing.Outputs()
+	for v := range 10 {
+		assert.Nil(t, failing.Submit(v), "submission")
+	}
+	for range 10 {
+		<-outputs
+	}
+	failing.Wait()
+	assert.Equal(t, 2, failing.Laborers(), "laborers")
+	assert.EqualValues(t, 10, failing.JobsCompleted(), "completed")
+	assert.Eventually(t, func() bool { return initErrs.Load() == 10 }, time.Second, time.Millisecond, "init failures")
+	failing.Close()
+}
+
 func squareSimple(v int) {
 	v *= v
 }
@@ -0,0 +1,201 @@
+package komi
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+// SubmitWithPriority sends a job with the given priority to the pool for processing,
+// jobs with higher priorities are picked up by laborers first. The pool has to be
+// prioritized, see `Settings.Prioritized`.
+func (p Pool[I, _]) SubmitWithPriority(job I, priority int) error {
+	return p.SubmitWithPriorityContext(p.ctx, job, priority)
+}
+
+// SubmitWithPriorityContext is `SubmitWithPriority` with a context, see `SubmitContext`.
+func (p Pool[I, _]) SubmitWithPriorityContext(ctx context.Context, job I, priority int) error {
+	if p.scheduler == nil {
+		return errors.New("the pool isn't prioritized")
+	}
+	release, err := p.openIntake(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.submitWithPolicy(ctx, task[I]{job: job, ctx: ctx, priority: priority})
+}
+
+// JobsWaitingByPriority will return the number of jobs waiting in the priority queue
+// and currently in-work by their priorities, empty if the pool isn't prioritized.
+func (p Pool[_, _]) JobsWaitingByPriority() map[int]int64 {
+	if p.scheduler == nil {
+		return map[int]int64{}
+	}
+	p.scheduler.lock.Lock()
+	defer p.scheduler.lock.Unlock()
+	return maps.Clone(p.scheduler.waiting)
+}
+
+// scheduler holds the submitted jobs in a priority queue, feeding laborers
+// the ones with the highest priority first.
+type scheduler[I any] struct {
+	// queue is the priority queue of the tasks.
+	queue priorityQueue[I]
+
+	// epoch is when the scheduler was created, aging is measured from it.
+	epoch time.Time
+
+	// aging is how long a task has to wait for its priority to go up by one.
+	aging time.Duration
+
+	// pushed is the number of tasks pushed so far, used to break ties.
+	pushed uint64
+
+	// lock guards the waiting counters.
+	lock sync.Mutex
+
+	// waiting are the numbers of tasks queued or in-work by their priorities.
+	waiting map[int]int64
+
+	// done is closed when the scheduler quits.
+	done chan Signal
+}
+
+// newScheduler creates a new scheduler with the given aging.
+func newScheduler[I any](aging time.Duration) *scheduler[I] {
+	return &scheduler[I]{
+		epoch:   time.Now(),
+		aging:   aging,
+		waiting: map[int]int64{},
+		done:    make(chan Signal),
+	}
+}
+
+// push will add the task to the priority queue.
+func (s *scheduler[I]) push(t task[I]) {
+	// A task's rank is its priority raised by one for every aging period it's
+	// waited, computed relative to the epoch, so the ranks don't change over time.
+	rank := int64(t.priority)*int64(s.aging) - int64(t.submitted.Sub(s.epoch))
+	heap.Push(&s.queue, scheduled[I]{task: t, rank: rank, order: s.pushed})
+	s.pushed++
+
+	s.lock.Lock()
+	s.waiting[t.priority]++
+	s.lock.Unlock()
+}
+
+// removeOldest will remove the task waiting the longest from the priority queue.
+func (s *scheduler[I]) removeOldest() task[I] {
+	oldest := 0
+	for i := range s.queue {
+		if s.queue[i].order < s.queue[oldest].order {
+			oldest = i
+		}
+	}
+	t := heap.Remove(&s.queue, oldest).(scheduled[I]).task
+	s.finished(t)
+	return t
+}
+
+// finished will record that the task is no longer queued or in-work.
+func (s *scheduler[I]) finished(t task[I]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.waiting[t.priority]--; s.waiting[t.priority] < 1 {
+		delete(s.waiting, t.priority)
+	}
+}
+
+// schedule is the scheduler's loop, which takes the submitted tasks from the
+// inputs channel and hands the highest ranked ones to laborers, until the
+// laborers are told to quit or the pool's context is done.
+func (p *Pool[I, _]) schedule() {
+	defer close(p.scheduler.done)
+	dropOldest := p.settings.OverflowPolicy == OverflowDropOldest
+	for {
+		// Take more tasks only if there is room for them, unless the oldest
+		// ones can be dropped. Nil channels disable their cases.
+		var intake chan task[I]
+		if len(p.scheduler.queue) < p.settings.Size || dropOldest {
+			intake = p.inputs
+		}
+		var ready chan task[I]
+		var next task[I]
+		if len(p.scheduler.queue) > 0 {
+			ready = p.ready
+			next = p.scheduler.queue[0].task
+		}
+		select {
+		case t := <-intake:
+			if len(p.scheduler.queue) >= p.settings.Size {
+				oldest := p.scheduler.removeOldest()
+				p.unqueued(oldest)
+				p.drop(oldest.job)
+			}
+			p.scheduler.push(t)
+		case ready <- next:
+			heap.Pop(&p.scheduler.queue)
+		case <-p.laborersStopSignal:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// takeScheduled will wait for the scheduler to quit and take the tasks
+// still in the priority queue, highest ranked first.
+func (p *Pool[I, _]) takeScheduled() []task[I] {
+	<-p.scheduler.done
+	tasks := make([]task[I], 0, len(p.scheduler.queue))
+	for len(p.scheduler.queue) > 0 {
+		t := heap.Pop(&p.scheduler.queue).(scheduled[I]).task
+		p.scheduler.finished(t)
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// scheduled is a task in the priority queue.
+type scheduled[I any] struct {
+	// task is the queued task.
+	task task[I]
+
+	// rank is the task's aged priority, higher ranks go first.
+	rank int64
+
+	// order is the number of tasks pushed before this one, it breaks ties.
+	order uint64
+}
+
+// priorityQueue is a max-heap of scheduled tasks, see `container/heap`.
+type priorityQueue[I any] []scheduled[I]
+
+// Len is the number of tasks in the queue.
+func (q priorityQueue[I]) Len() int { return len(q) }
+
+// Less puts higher ranks first and the earlier pushed ones on ties.
+func (q priorityQueue[I]) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	return q[i].order < q[j].order
+}
+
+// Swap swaps two tasks in the queue.
+func (q priorityQueue[I]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+// Push adds a task to the end of the queue.
+func (q *priorityQueue[I]) Push(x any) { *q = append(*q, x.(scheduled[I])) }
+
+// Pop removes the task from the end of the queue.
+func (q *priorityQueue[I]) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,141 @@
+package komi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RateLimit limits how many jobs laborers can start per interval, shared by
+// all laborers of the pool. Retries count as new jobs.
+type RateLimit struct {
+	// Jobs is the number of jobs that can be started per interval.
+	Jobs int
+
+	// Interval is the period the number of jobs is given for, defaults to a second.
+	Interval time.Duration
+
+	// Burst is the number of jobs that can be started at once when the limit
+	// is set or after laborers have been idle, defaults to `Jobs`.
+	Burst int
+}
+
+// verifyRateLimit will set sensible defaults for the rate limit.
+func verifyRateLimit(limit *RateLimit) error {
+	if limit.Jobs < 1 {
+		return errors.New("rate limit should allow at least one job")
+	}
+	if limit.Interval <= 0 {
+		limit.Interval = time.Second
+	}
+	if limit.Burst < 1 {
+		limit.Burst = limit.Jobs
+	}
+	return nil
+}
+
+// rateLimiter is a token bucket, where every started job takes a token.
+type rateLimiter struct {
+	// lock guards the bucket.
+	lock sync.Mutex
+
+	// rate is the number of tokens added per second, no limit if zero.
+	rate float64
+
+	// burst is the capacity of the bucket.
+	burst float64
+
+	// tokens is the number of tokens in the bucket, negative if jobs are
+	// waiting for tokens that haven't been added yet.
+	tokens float64
+
+	// last is when tokens were last added.
+	last time.Time
+}
+
+// set will change the limit, removing it if nil.
+func (l *rateLimiter) set(limit *RateLimit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if limit == nil {
+		l.rate = 0
+		return
+	}
+	// Without a limit so far, laborers were free to start jobs, so they
+	// get the whole burst right away.
+	if l.rate == 0 {
+		l.tokens = float64(limit.Burst)
+	}
+	l.rate = float64(limit.Jobs) / limit.Interval.Seconds()
+	l.burst = float64(limit.Burst)
+	l.tokens = min(l.tokens, l.burst)
+	l.last = time.Now()
+}
+
+// reserve will take a token, returning how long to wait until it's added.
+func (l *rateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.rate == 0 {
+		return 0
+	}
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// unreserve will put back a token that wasn't used.
+func (l *rateLimiter) unreserve() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tokens = min(l.tokens+1, l.burst)
+}
+
+// wait will block until a token is available or the context is done,
+// returning how long it waited.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	delay := l.reserve()
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	start := time.Now()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		l.unreserve()
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// SetRateLimit will change the pool's rate limit, removing it if nil.
+func (p *Pool[_, _]) SetRateLimit(limit *RateLimit) error {
+	if limit != nil {
+		if err := verifyRateLimit(limit); err != nil {
+			return err
+		}
+	}
+	p.limiter.set(limit)
+	return nil
+}
+
+// TimeThrottled will return the total time laborers have spent waiting
+// for the rate limit before starting jobs.
+func (p Pool[_, _]) TimeThrottled() time.Duration {
+	return time.Duration(p.timeThrottled.Load())
+}
+
+// throttle will block until the rate limit allows starting a job.
+func (p *Pool[_, _]) throttle(ctx context.Context) error {
+	waited, err := p.limiter.wait(ctx)
+	p.timeThrottled.Add(int64(waited))
+	return err
+}
@@ -0,0 +1,137 @@
+package komi
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultAutoscaleInterval is how often the autoscaler checks the load by default.
+	defaultAutoscaleInterval = time.Second
+)
+
+// Autoscale sets the bounds and the pace of adjusting the number of laborers
+// to the load, which is told by the number of jobs waiting in the queue and
+// how long they wait there before laborers pick them up.
+type Autoscale struct {
+	// MinLaborers is the least number of laborers, defaults to 1.
+	MinLaborers int
+
+	// MaxLaborers is the most number of laborers, defaults to the minimum.
+	MaxLaborers int
+
+	// Interval is how often the load is checked, defaults to a second.
+	Interval time.Duration
+
+	// TargetLatency is how long jobs can wait in the queue on average before
+	// more laborers are added. If zero, any jobs waiting in the queue are enough.
+	TargetLatency time.Duration
+}
+
+// verifyAutoscale will set sensible defaults for the autoscaling bounds.
+func verifyAutoscale(autoscale *Autoscale) {
+	autoscale.MinLaborers = max(autoscale.MinLaborers, 1)
+	autoscale.MaxLaborers = max(autoscale.MaxLaborers, autoscale.MinLaborers)
+	if autoscale.Interval <= 0 {
+		autoscale.Interval = defaultAutoscaleInterval
+	}
+}
+
+// SetLaborers will grow or shrink the number of laborers to `n`. New laborers
+// are started right away, while the extra ones quit after their current job.
+func (p *Pool[_, _]) SetLaborers(n int) error {
+	if n < 1 {
+		return errors.New("a pool needs at least one laborer")
+	}
+
+	p.laborersLock.Lock()
+	defer p.laborersLock.Unlock()
+
+	// Refuse to change anything if laborers have been told to quit.
+	select {
+	case <-p.laborersStopSignal:
+		return fmt.Errorf("can't set laborers: %w", ErrPoolClosed)
+	default:
+	}
+
+	diff := n - int(p.laborersWanted.Swap(int64(n)))
+	for i := 0; i < diff; i++ {
+		p.spawnLaborer()
+	}
+	for i := 0; i < -diff; i++ {
+		// Laborers pick up the retire signal between jobs, so don't block on it.
+		go func() {
+			select {
+			case p.laborersRetireSignal <- signal:
+			case <-p.laborersStopSignal:
+			}
+		}()
+	}
+	if diff != 0 {
+		p.log.Debug("Set laborers", "count", n, "diff", diff)
+	}
+	return nil
+}
+
+// Laborers returns the number of currently running laborers.
+func (p Pool[_, _]) Laborers() int {
+	return int(p.laborersCount.Load())
+}
+
+// recordQueueWait will record how long the task has waited in the queue.
+func (p *Pool[I, _]) recordQueueWait(t task[I]) {
+	waited := time.Since(t.submitted)
+	p.queueWaitTotal.Add(int64(waited))
+	p.queueWaitCount.Add(1)
+	p.queueWaitTimes.observe(waited)
+}
+
+// autoscale will periodically adjust the number of laborers to the load,
+// until the laborers are told to quit or the pool's context is done.
+func (p *Pool[_, _]) autoscale() {
+	autoscale := p.settings.Autoscale
+	ticker := time.NewTicker(autoscale.Interval)
+	defer ticker.Stop()
+
+	waitTotal, waitCount := p.queueWaitTotal.Load(), p.queueWaitCount.Load()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.laborersStopSignal:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+
+		// Average queue latency of jobs picked up since the last check.
+		latency := time.Duration(0)
+		newWaitTotal, newWaitCount := p.queueWaitTotal.Load(), p.queueWaitCount.Load()
+		if newWaitCount > waitCount {
+			latency = time.Duration((newWaitTotal - waitTotal) / (newWaitCount - waitCount))
+		}
+		waitTotal, waitCount = newWaitTotal, newWaitCount
+
+		current := int(p.laborersWanted.Load())
+		busy := int(p.laborersBusy.Load())
+		queued := max(int(p.jobsWaiting.Load())-busy, 0)
+		wanted := current
+		switch {
+		case queued > 0 && latency >= autoscale.TargetLatency:
+			// Jobs are piling up, add up to as many laborers as there are
+			// queued jobs, but at most double them at a time.
+			wanted = current + min(queued, current)
+		case queued == 0 && busy < current:
+			// Laborers are idling, let half of the idle ones go.
+			wanted = current - (current-busy+1)/2
+		}
+		wanted = min(max(wanted, autoscale.MinLaborers), autoscale.MaxLaborers)
+		if wanted == current {
+			continue
+		}
+		p.log.Debug("Autoscaling laborers", "from", current, "to", wanted, "queued", queued, "latency", latency)
+		if err := p.SetLaborers(wanted); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package komi
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// defaultRetryMultiplier is the default growth of backoff between attempts.
+	defaultRetryMultiplier = 2
+)
+
+// RetryPolicy tells the pool how to retry jobs whose work returned a non-nil
+// error, before reporting them as failed.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times work is performed on a job,
+	// including the first attempt. Retries are disabled if it's below 2.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, no cap if zero.
+	MaxBackoff time.Duration
+
+	// Multiplier is what the delay is multiplied by after each retry, so the
+	// backoff grows exponentially. Defaults to 2.
+	Multiplier float64
+
+	// Jitter randomizes the delay by up to this fraction of it in either
+	// direction, so retries of many jobs don't happen in lockstep. It's
+	// clamped between 0 (no jitter) and 1.
+	Jitter float64
+
+	// Retryable decides if the error is worth retrying, if nil,
+	// all errors are retried.
+	Retryable func(error) bool
+}
+
+// verifyRetryPolicy will set sensible defaults for the retry policy.
+func verifyRetryPolicy(retry *RetryPolicy) {
+	if retry.Multiplier <= 0 {
+		retry.Multiplier = defaultRetryMultiplier
+	}
+	retry.Jitter = math.Min(math.Max(retry.Jitter, 0), 1)
+}
+
+// allows returns true if the job should be attempted again after it failed
+// with the error on the given attempt. Panics are never retried.
+func (r *RetryPolicy) allows(attempt int, err error) bool {
+	if r == nil || attempt >= r.MaxAttempts || errors.Is(err, ErrJobPanicked) {
+		return false
+	}
+	return r.Retryable == nil || r.Retryable(err)
+}
+
+// delay returns how long to wait after the given failed attempt.
+func (r *RetryPolicy) delay(attempt int) time.Duration {
+	delay := float64(r.Backoff) * math.Pow(r.Multiplier, float64(attempt-1))
+	if r.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(r.MaxBackoff))
+	}
+	if r.Jitter > 0 {
+		delay += delay * r.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
@@ -1,6 +1,10 @@
 package komi
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
 	"github.com/charmbracelet/log"
 )
 
@@ -29,8 +33,100 @@ type Settings struct {
 	// Name is the Name of the pool.
 	Name string
 
-	// LogLevel defaults to warn, can be set by the user.
+	// LogLevel defaults to warn, can be set by the user. It only applies
+	// to the default logger, same as `Debug`.
 	LogLevel log.Level
+
+	// Logger is the structured logger the pool logs to, with the pool's name
+	// as the "pool" attribute. Defaults to a logger writing to stderr.
+	Logger *slog.Logger
+
+	// LogHandler is the structured log handler the pool logs to, same as `Logger`,
+	// which takes precedence if both are set.
+	LogHandler slog.Handler
+
+	// DisableLogging will make the pool log nothing at all.
+	DisableLogging bool
+
+	// Context is the pool-level context, when it's cancelled, laborers
+	// stop picking up jobs and blocked submitters return its error. Every
+	// job's context is derived from it. Defaults to `context.Background()`.
+	Context context.Context
+
+	// RestartOnPanic will replace a laborer that recovered from a panic in work
+	// with a fresh one, otherwise, or if the fresh one fails to initialize (see
+	// `WorkWithState`), the laborer keeps picking up jobs.
+	RestartOnPanic bool
+
+	// LaborerMaxJobs is how many jobs a laborer performs before it's replaced with
+	// a fresh one, tearing down its state (see `WorkWithState`). If the fresh one
+	// fails to initialize, the laborer performs as many jobs more. Never if zero.
+	LaborerMaxJobs int
+
+	// LaborerMaxAge is how long a laborer runs before it's replaced with a fresh
+	// one, after its current job, tearing down its state (see `WorkWithState`). If
+	// the fresh one fails to initialize, the laborer runs as long more. Never if zero.
+	LaborerMaxAge time.Duration
+
+	// Retry sets the policy of retrying jobs that failed with non-nil errors,
+	// no retries are made if it's nil.
+	Retry *RetryPolicy
+
+	// RateLimit limits how many jobs laborers can start per interval, it can
+	// be changed later with `SetRateLimit`. No limit if nil.
+	RateLimit *RateLimit
+
+	// JobTimeout is how long a job can be worked on (including retries), after
+	// which its context is cancelled and the laborer abandons it, moving on to the
+	// next job. The job is reported as failed with an error wrapping `ErrJobTimeout`.
+	// Jobs submitted with a context that has a deadline use that deadline instead.
+	// No timeout if zero.
+	JobTimeout time.Duration
+
+	// Autoscale will periodically adjust the number of laborers to the load,
+	// within the given bounds, no autoscaling if nil.
+	Autoscale *Autoscale
+
+	// Ordered will make the pool release outputs and errors in the order
+	// the jobs were submitted, instead of the order they completed in.
+	Ordered bool
+
+	// Prioritized will make laborers pick up jobs with higher priorities first,
+	// see `SubmitWithPriority`. Up to the size of the pool jobs wait in the
+	// priority queue, the rest wait in submission order to get in. An ordered
+	// pool can't be prioritized.
+	Prioritized bool
+
+	// Aging is how long a job has to wait in the priority queue for its priority
+	// to go up by one, so jobs with low priorities are not starved by the ones
+	// with high priorities. Defaults to a second.
+	Aging time.Duration
+
+	// OverflowPolicy decides what happens to jobs submitted with `Submit` (or
+	// sent by connected pools) when the pool is full, blocks by default.
+	OverflowPolicy OverflowPolicy
+
+	// DeadLetters will make the pool send jobs dropped or rejected by the overflow
+	// policy to a channel, see `DeadLetters`. If it's full, dropped jobs are logged.
+	DeadLetters bool
+
+	// ConcurrentOutputs will let laborers call the output handler (see `OnOutput`)
+	// concurrently, otherwise, the calls are made one at a time.
+	ConcurrentOutputs bool
+
+	// BatchSize is the most number of jobs performed together, if work is
+	// batched (see `WorkBatch`). Defaults to the size of the pool.
+	BatchSize int
+
+	// BatchLinger is how long a laborer waits for more jobs to fill up the batch,
+	// after picking up its first job. If zero, batches only take the jobs that
+	// are already waiting in the queue.
+	BatchLinger time.Duration
+
+	// ReorderWindow is how many jobs ahead of the oldest unreleased one laborers
+	// can work on when the pool is ordered, bounding how many outputs and errors
+	// are held back. Defaults to the size of the pool.
+	ReorderWindow int
 }
 
 // verifySettings will make sure the settings are proper and
@@ -40,6 +136,12 @@ func verifySettings(settings *Settings) {
 	if settings.Laborers <= 0 {
 		settings.Laborers = defaultNumLaborers
 	}
+	// If autoscaling is requested, make sure the bounds are sound and
+	// the laborers are within them.
+	if settings.Autoscale != nil {
+		verifyAutoscale(settings.Autoscale)
+		settings.Laborers = min(max(settings.Laborers, settings.Autoscale.MinLaborers), settings.Autoscale.MaxLaborers)
+	}
 	// If the user has manually set the size, that shall be used.
 	if settings.Size > 0 {
 		settings.sizeOverride = true
@@ -64,4 +166,24 @@ func verifySettings(settings *Settings) {
 	if len(settings.Name) < 1 {
 		settings.Name = defaultName
 	}
+	// If the reorder window is not set, default to the size.
+	if settings.ReorderWindow <= 0 {
+		settings.ReorderWindow = settings.Size
+	}
+	// If the batch size is not set, default to the size.
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = settings.Size
+	}
+	// If the pool is prioritized, set the default aging.
+	if settings.Prioritized && settings.Aging <= 0 {
+		settings.Aging = defaultAging
+	}
+	// If retries are requested, make sure the policy is sound.
+	if settings.Retry != nil {
+		verifyRetryPolicy(settings.Retry)
+	}
+	// If no context is given, the pool lives until it's closed.
+	if settings.Context == nil {
+		settings.Context = context.Background()
+	}
 }
@@ -0,0 +1,207 @@
+package komi
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// ShutdownReport tells what happened to the pool's jobs during the shutdown.
+type ShutdownReport[I any] struct {
+	// Unprocessed are the jobs that were never started, in submission order.
+	Unprocessed []I
+
+	// Completed is the number of jobs completed during the shutdown.
+	Completed int64
+
+	// Failed is the number of the completed jobs that failed.
+	Failed int64
+
+	// Abandoned is the number of jobs still in-work when the deadline passed,
+	// their outputs and errors are discarded.
+	Abandoned int64
+
+	// ChildrenDiscarded is the number of jobs the dependent (child) pools still had
+	// waiting (queued or in-work) when the deadline passed. The queued ones are
+	// discarded, while the outputs of the ones in-work never reach this pool.
+	ChildrenDiscarded int64
+}
+
+// Shutdown will gracefully close the pool, it stops taking jobs right away and lets
+// laborers finish the jobs in-work until the context is done. The jobs that were never
+// started are returned in the report, instead of being discarded, so they can be
+// persisted or submitted again. If the context is done before laborers finish, the
+// jobs in-work are abandoned (see `ShutdownReport`), and the context's error is
+// returned. Outputs and errors already sent are left in their channels, which are
+// not closed if jobs were abandoned. Same as `Close`, only a disconnected or the
+// parent-most pool can be shut down, its children finish their jobs (until the
+// context is done) and are closed first.
+func (p *Pool[I, _]) Shutdown(ctx context.Context) (ShutdownReport[I], error) {
+	if p.State() >= StateClosing {
+		return ShutdownReport[I]{}, ErrPoolClosed
+	}
+	report := &ShutdownReport[I]{}
+	request := closure[I]{shutdown: ctx, report: report, done: make(chan error, 1)}
+	select {
+	case p.closureRequest <- request:
+	case <-p.closedSignal:
+		// The closure subroutine has left, as the pool got closed meanwhile.
+		return ShutdownReport[I]{}, ErrPoolClosed
+	}
+	err := <-request.done
+	return *report, err
+}
+
+// shutdown is the closure subroutine of a graceful shutdown, see `Shutdown`.
+func (p *Pool[I, _]) shutdown(request closure[I]) {
+	ctx := request.shutdown
+	completed, succeeded := p.jobsCompleted.Load(), p.jobsSucceeded.Load()
+
+	// Wait for the children to finish their jobs, they can send jobs here until
+	// they leave. If the deadline passes first, they discard the rest.
+	p.setState(StateDraining)
+	childrenCut := false
+	if p.anotherPoolIsSendingJobsHere() {
+		closureSignals, childrenWaits := p.children.connected()
+		p.log.Debug("Waiting for the children's Wait", "children", len(childrenWaits))
+		if !waitBefore(ctx, childrenWaits) {
+			childrenCut = true
+			request.report.ChildrenDiscarded = p.children.waiting()
+			p.log.Warn("Children didn't finish their jobs before the shutdown deadline",
+				"waiting", request.report.ChildrenDiscarded)
+		}
+		p.log.Info("Sending a signal for the children to leave...", "children", len(closureSignals))
+		close(p.tellChildrenToClose)
+		for _, closureSignal := range closureSignals {
+			select {
+			case <-closureSignal:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	// Stop taking jobs at all, and let laborers finish the ones in-work.
+	p.stopIntake()
+	waiting, timedOut := p.stopLaborersBefore(ctx)
+
+	// Take the jobs that were never started, once the scheduler and the
+	// feeder stop moving them around.
+	tasks := p.leftovers.take()
+	if p.scheduler != nil {
+		tasks = append(tasks, p.takeScheduled()...)
+	}
+	if p.overflow != nil {
+		tasks = append(tasks, p.takeOverflow()...)
+	}
+	tasks = append(tasks, take(p.inputs)...)
+
+	// Whatever is still waiting, but was never started, is in-work.
+	abandoned := int64(0)
+	if timedOut {
+		abandoned = waiting - int64(len(tasks))
+		p.log.Warn("Abandoned the jobs in-work after the shutdown deadline", "abandoned", abandoned)
+	}
+	slices.SortStableFunc(tasks, func(a, b task[I]) int { return a.submitted.Compare(b.submitted) })
+	for _, t := range tasks {
+		p.unqueued(t)
+		request.report.Unprocessed = append(request.report.Unprocessed, t.job)
+	}
+	request.report.Completed = p.jobsCompleted.Load() - completed
+	request.report.Failed = request.report.Completed - (p.jobsSucceeded.Load() - succeeded)
+	request.report.Abandoned = abandoned
+
+	if p.deadLetters != nil {
+		close(p.deadLetters)
+	}
+
+	// Laborers of the abandoned jobs are still around, so keep the channels open.
+	if !timedOut {
+		close(p.inputs)
+		if p.outputs != nil {
+			close(p.outputs)
+		}
+		if p.errors != nil {
+			close(p.errors)
+		}
+	}
+
+	p.setState(StateClosed)
+	p.log.Debug("Pool is shut down", "completed", request.report.Completed,
+		"unprocessed", len(tasks), "abandoned", abandoned)
+
+	p.closedSignal <- signal
+	close(p.closedSignal)
+	p.releaseParents()
+	p.cancel()
+
+	if timedOut || childrenCut {
+		request.reply(ctx.Err())
+		return
+	}
+	request.reply(nil)
+}
+
+// waitBefore will call the wait functions one after another and wait (blocking)
+// until they all return or the context is done. Returns true if they all returned.
+func waitBefore(ctx context.Context, waits []func()) bool {
+	returned := make(chan Signal)
+	go func() {
+		for _, wait := range waits {
+			wait()
+		}
+		close(returned)
+	}()
+	select {
+	case <-returned:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopLaborersBefore will send closure signals to all laborers and wait (blocking)
+// until they all leave or the context is done, in which case, the outcomes of the
+// jobs in-work are discarded and their contexts are cancelled. Returns the number
+// of jobs waiting (queued or in-work) at the deadline and true if it passed.
+func (p *Pool[_, _]) stopLaborersBefore(ctx context.Context) (int64, bool) {
+	left := make(chan Signal)
+	go func() {
+		p.stopLaborers()
+		close(left)
+	}()
+	select {
+	case <-left:
+		return 0, false
+	case <-ctx.Done():
+	}
+	p.discardOutcomes.Store(true)
+	waiting := p.jobsWaiting.Load()
+	p.cancel()
+	return waiting, true
+}
+
+// leftovers are the tasks laborers picked up after they were told to quit,
+// which they leave for the closure, instead of starting them.
+type leftovers[I any] struct {
+	// lock guards the tasks.
+	lock sync.Mutex
+
+	// tasks are the tasks left by laborers.
+	tasks []task[I]
+}
+
+// put will leave the task for the closure.
+func (l *leftovers[I]) put(t task[I]) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tasks = append(l.tasks, t)
+}
+
+// take will remove and return the tasks left by laborers.
+func (l *leftovers[I]) take() []task[I] {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	tasks := l.tasks
+	l.tasks = nil
+	return tasks
+}
@@ -0,0 +1,106 @@
+package komi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// laborerInitRetry is the backoff between the attempts to initialize a laborer
+// replacing one that can't keep working.
+var laborerInitRetry = &RetryPolicy{
+	Backoff:    10 * time.Millisecond,
+	MaxBackoff: time.Second,
+	Multiplier: defaultRetryMultiplier,
+	Jitter:     0.1,
+}
+
+// laborer is what a running laborer performs work with.
+type laborer[I, O any] struct {
+	// id is the laborer's ID, unique among the running laborers.
+	id int
+
+	// work is the laborer's work performer, bound to its state, if any.
+	work func(context.Context, I) (O, error)
+
+	// teardown releases the laborer's state, nil if it has none.
+	teardown func()
+
+	// abandoned is closed once the job abandoned by the laborer returns, nil
+	// if the laborer has state and hasn't abandoned any jobs.
+	abandoned chan Signal
+}
+
+// newLaborer will take an ID for a new laborer and initialize its state,
+// if work has any, returning the error of the state's init function.
+func (p *Pool[I, O]) newLaborer() (*laborer[I, O], error) {
+	id := p.laborerIDs.take()
+	if !p.isWorkState() {
+		return &laborer[I, O]{id: id, work: p.workPerformer}, nil
+	}
+	work, teardown, err := p.workState(id)
+	if err != nil {
+		p.laborerIDs.put(id)
+		return nil, fmt.Errorf("%w %d: %w", ErrLaborerInit, id, err)
+	}
+	return &laborer[I, O]{id: id, work: work, teardown: teardown}, nil
+}
+
+// laborerFailed will report the error of initializing a laborer, as a pool
+// error without a job.
+func (p *Pool[I, _]) laborerFailed(err error) {
+	p.log.Warn("Laborer failed to start", "err", err)
+	p.reportError(PoolError[I]{Error: err})
+}
+
+// retireLaborer will tear down the laborer's state, if any, and give up its ID. If
+// the laborer has abandoned a job, it's done once the job returns, as the job might
+// still be using the state.
+func (p *Pool[I, O]) retireLaborer(l *laborer[I, O]) {
+	if l.abandoned != nil {
+		abandoned := l.abandoned
+		l.abandoned = nil
+		go func() {
+			<-abandoned
+			p.retireLaborer(l)
+		}()
+		return
+	}
+	if l.teardown != nil {
+		l.teardown()
+	}
+	p.laborerIDs.put(l.id)
+}
+
+// laborerIDs hands out the lowest ID not taken by a running laborer, so the
+// IDs stay below the highest number of laborers the pool has had.
+type laborerIDs struct {
+	// lock guards the IDs.
+	lock sync.Mutex
+
+	// taken are the IDs of the running laborers.
+	taken map[int]bool
+}
+
+// take will return the lowest free ID, marking it taken.
+func (ids *laborerIDs) take() int {
+	ids.lock.Lock()
+	defer ids.lock.Unlock()
+	if ids.taken == nil {
+		ids.taken = map[int]bool{}
+	}
+	id := 0
+	for ids.taken[id] {
+		id++
+	}
+	ids.taken[id] = true
+	return id
+}
+
+// put will free the ID.
+func (ids *laborerIDs) put(id int) {
+	ids.lock.Lock()
+	defer ids.lock.Unlock()
+	delete(ids.taken, id)
+}
@@ -0,0 +1,174 @@
+package komi
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultBuckets are the upper bounds of the latency histograms' buckets.
+var defaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// PoolStats is a snapshot of the pool's counters.
+type PoolStats struct {
+	// Name is the name of the pool.
+	Name string
+
+	// JobsCompleted is the number of jobs completed by the pool.
+	JobsCompleted int64
+
+	// JobsSucceeded is the number of jobs completed with nil errors.
+	JobsSucceeded int64
+
+	// JobsFailed is the number of jobs completed with non-nil errors.
+	JobsFailed int64
+
+	// JobsWaiting is the number of jobs waiting in the queue and in-work.
+	JobsWaiting int64
+
+	// JobsRetried is the number of retries made, see `Settings.Retry`.
+	JobsRetried int64
+
+	// JobsRejected is the number of jobs rejected because the pool was full.
+	JobsRejected int64
+
+	// JobsDropped is the number of jobs dropped or rejected by the overflow policy.
+	JobsDropped int64
+
+	// QueueDepth is the number of jobs waiting in the queue, but not in-work.
+	QueueDepth int64
+
+	// LaborersBusy is the number of laborers performing work.
+	LaborersBusy int64
+
+	// LaborersIdle is the number of laborers waiting for jobs.
+	LaborersIdle int64
+
+	// OutputsBacklog is the number of outputs waiting to be read from `Outputs`.
+	OutputsBacklog int
+
+	// ErrorsBacklog is the number of errors waiting to be read from `Errors`.
+	ErrorsBacklog int
+
+	// Throughput is the average number of jobs completed per second since
+	// the pool was created.
+	Throughput float64
+
+	// TimeThrottled is the total time laborers have waited for the rate limit.
+	TimeThrottled time.Duration
+
+	// QueueWait is the histogram of how long jobs waited in the queue
+	// before laborers picked them up.
+	QueueWait Histogram
+
+	// Execution is the histogram of how long work was performed on jobs,
+	// including retries.
+	Execution Histogram
+}
+
+// Histogram is a snapshot of the distribution of durations.
+type Histogram struct {
+	// Buckets are the cumulative counts of durations up to their upper bounds,
+	// durations above the last bound are only counted in `Count`.
+	Buckets []Bucket
+
+	// Count is the number of observed durations.
+	Count int64
+
+	// Sum is the total of observed durations.
+	Sum time.Duration
+}
+
+// Bucket is the number of observed durations up to the upper bound.
+type Bucket struct {
+	// UpperBound is the longest duration counted in the bucket.
+	UpperBound time.Duration
+
+	// Count is the number of durations up to the upper bound.
+	Count int64
+}
+
+// Stats returns a snapshot of the pool's counters.
+func (p Pool[_, _]) Stats() PoolStats {
+	completed, succeeded := p.jobsCompleted.Load(), p.jobsSucceeded.Load()
+	waiting, busy, laborers := p.jobsWaiting.Load(), p.laborersBusy.Load(), p.laborersCount.Load()
+	throughput := 0.0
+	if elapsed := time.Since(p.created).Seconds(); elapsed > 0 {
+		throughput = float64(completed) / elapsed
+	}
+	return PoolStats{
+		Name:           p.Name(),
+		JobsCompleted:  completed,
+		JobsSucceeded:  succeeded,
+		JobsFailed:     completed - succeeded,
+		JobsWaiting:    waiting,
+		JobsRetried:    p.jobsRetried.Load(),
+		JobsRejected:   p.jobsRejected.Load(),
+		JobsDropped:    p.jobsDropped.Load(),
+		QueueDepth:     max(waiting-busy, 0),
+		LaborersBusy:   busy,
+		LaborersIdle:   max(laborers-busy, 0),
+		OutputsBacklog: len(p.outputs),
+		ErrorsBacklog:  len(p.errors),
+		Throughput:     throughput,
+		TimeThrottled:  p.TimeThrottled(),
+		QueueWait:      p.queueWaitTimes.snapshot(),
+		Execution:      p.executionTimes.snapshot(),
+	}
+}
+
+// histogram counts the observed durations into buckets.
+type histogram struct {
+	// counts are the non-cumulative counts of the buckets, the last one
+	// counts the durations above all the bounds.
+	counts []atomic.Int64
+
+	// count is the number of observed durations.
+	count atomic.Int64
+
+	// sum is the total of observed durations, in nanoseconds.
+	sum atomic.Int64
+}
+
+// newHistogram creates a new histogram with the default buckets.
+func newHistogram() *histogram {
+	return &histogram{counts: make([]atomic.Int64, len(defaultBuckets)+1)}
+}
+
+// observe will count the duration.
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(defaultBuckets) && d > defaultBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+// snapshot returns the cumulative counts of the histogram.
+func (h *histogram) snapshot() Histogram {
+	snapshot := Histogram{
+		Buckets: make([]Bucket, len(defaultBuckets)),
+		Count:   h.count.Load(),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+	cumulative := int64(0)
+	for i, bound := range defaultBuckets {
+		cumulative += h.counts[i].Load()
+		snapshot.Buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return snapshot
+}
@@ -1,5 +1,7 @@
 package komi
 
+import "sync"
+
 // drain will remove any pending values from the channel.
 func drain[T any](v chan T) {
 	for {
@@ -12,5 +14,51 @@ func drain[T any](v chan T) {
 	}
 }
 
+// take will remove and return any pending values from the channel.
+func take[T any](v chan T) []T {
+	var values []T
+	for {
+		select {
+		case vv, ok := <-v:
+			if !ok {
+				return values
+			}
+			values = append(values, vv)
+		default:
+			return values
+		}
+	}
+}
+
 // nop is a no-op (does nothing).
 func nop(v any) {}
+
+// broadcaster hands out a channel that gets closed on broadcast, so any
+// number of goroutines can be woken up at once, and then replaced.
+type broadcaster struct {
+	// lock guards the channel.
+	lock sync.Mutex
+
+	// ch is the channel closed on the next broadcast.
+	ch chan Signal
+}
+
+// newBroadcaster creates a new broadcaster.
+func newBroadcaster() *broadcaster {
+	return &broadcaster{ch: make(chan Signal)}
+}
+
+// wait returns the channel that will be closed on the next broadcast.
+func (b *broadcaster) wait() <-chan Signal {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.ch
+}
+
+// broadcast wakes up everyone waiting on the current channel.
+func (b *broadcaster) broadcast() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	close(b.ch)
+	b.ch = make(chan Signal)
+}
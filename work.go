@@ -1,5 +1,13 @@
 package komi
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
 // isWorkSimple returns true if the work produces no outputs nor errors.
 func (p *Pool[_, _]) isWorkSimple() bool { return p.workSimple != nil }
 
@@ -12,74 +20,293 @@ func (p *Pool[_, _]) isWorkRegular() bool { return p.workRegular != nil }
 // isWorkRegularWithErrors returns true if the work produces outputs and errors.
 func (p *Pool[_, _]) isWorkRegularWithErrors() bool { return p.workRegularWithErrors != nil }
 
+// isWorkCtx returns true if the work is context-aware and produces outputs and errors.
+func (p *Pool[_, _]) isWorkCtx() bool { return p.workCtx != nil }
+
+// isWorkBatch returns true if the work is performed on batches of jobs.
+func (p *Pool[_, _]) isWorkBatch() bool { return p.workBatch != nil }
+
+// isWorkState returns true if the work needs its own state in every laborer.
+func (p *Pool[_, _]) isWorkState() bool { return p.workState != nil }
+
 // hasWork returns true work has been set and is non-nil.
 func (p *Pool[_, _]) hasWork() bool {
-	return p.isWorkSimple() || p.isWorkSimpleWithErrors() || p.isWorkRegular() || p.isWorkRegularWithErrors()
+	return p.isWorkSimple() || p.isWorkSimpleWithErrors() || p.isWorkRegular() || p.isWorkRegularWithErrors() ||
+		p.isWorkCtx() || p.isWorkBatch() || p.isWorkState()
 }
 
 // producesOutputs returns true if the work produces outputs.
 func (p *Pool[_, _]) producesOutputs() bool {
-	return p.isWorkRegular() || p.isWorkRegularWithErrors()
+	return p.isWorkRegular() || p.isWorkRegularWithErrors() || p.isWorkCtx() || p.isWorkBatch() || p.isWorkState()
 }
 
 // producesErrors returns true if the work produces errors.
 func (p *Pool[_, _]) producesErrors() bool {
-	return p.isWorkSimpleWithErrors() || p.isWorkRegularWithErrors()
+	return p.isWorkSimpleWithErrors() || p.isWorkRegularWithErrors() || p.isWorkCtx() || p.isWorkBatch() ||
+		p.isWorkState()
 }
 
 // performWorkSimple will perform the simple work.
-func (p *Pool[I, _]) performWorkSimple(job I) {
-	defer p.performedWork(true)
+func (p *Pool[I, O]) performWorkSimple(_ context.Context, job I) (O, error) {
 	p.workSimple(job)
+	return *new(O), nil
 }
 
 // performWorkSimpleWithErrors will perform simple work with errors.
-func (p *Pool[I, _]) performWorkSimpleWithErrors(job I) {
-	err := p.workSimpleWithErrors(job)
+func (p *Pool[I, O]) performWorkSimpleWithErrors(_ context.Context, job I) (O, error) {
+	return *new(O), p.workSimpleWithErrors(job)
+}
+
+// performWorkRegular will perform regular work.
+func (p *Pool[I, O]) performWorkRegular(_ context.Context, job I) (O, error) {
+	return p.workRegular(job), nil
+}
+
+// performWorkWithErrors will perform regular work with errors.
+func (p *Pool[I, O]) performWorkWithErrors(_ context.Context, job I) (O, error) {
+	return p.workRegularWithErrors(job)
+}
+
+// performWorkCtx will perform context-aware work.
+func (p *Pool[I, O]) performWorkCtx(ctx context.Context, job I) (O, error) {
+	return p.workCtx(ctx, job)
+}
+
+// performWorkBatch will perform batched work on a batch of one job.
+func (p *Pool[I, O]) performWorkBatch(_ context.Context, job I) (O, error) {
+	outs, err := p.workBatch([]I{job})
+	if err = batchOutcomes(1, len(outs), err)[0]; err != nil {
+		return *new(O), err
+	}
+	return outs[0], nil
+}
+
+// perform will run the laborer's work performer on the task and send its
+// outputs and errors (if work produces them) to their channels.
+// Returns true if work panicked.
+func (p *Pool[I, O]) perform(l *laborer[I, O], t task[I]) bool {
+	ctx, cancel := p.jobContext(t)
+	defer cancel()
+
+	started := time.Now()
+	res, attempts, previous, err := p.attemptBeforeDeadline(ctx, l, t.job)
+	p.executionTimes.observe(time.Since(started))
 	if err != nil {
-		p.errors <- PoolError[I]{
-			Job:   job,
-			Error: err,
+		poolErr := newPoolError(t.job, err, attempts, previous)
+		p.release(t, func() { p.failed(t, poolErr) })
+		return poolErr.Panic != nil
+	}
+	p.release(t, func() { p.succeeded(t, res) })
+	return false
+}
+
+// newPoolError creates the pool error of the failed job, filling in the
+// panic value and stack, if work panicked.
+func newPoolError[I any](job I, err error, attempts int, previous []error) PoolError[I] {
+	poolErr := PoolError[I]{
+		Job:            job,
+		Error:          err,
+		Attempts:       attempts,
+		PreviousErrors: previous,
+	}
+	var pe *panicError
+	if errors.As(err, &pe) {
+		poolErr.Panic = pe.value
+		poolErr.Stack = pe.stack
+	}
+	return poolErr
+}
+
+// release will run the outcome (can be nil) of the task right away, or, if the pool
+// is ordered, once the outcomes of all the tasks submitted before it are released.
+func (p *Pool[I, _]) release(t task[I], outcome func()) {
+	if p.sequencer == nil {
+		if outcome != nil {
+			outcome()
 		}
-		p.performedWork(false)
 		return
 	}
+	p.sequencer.complete(t.seq, outcome)
+}
+
+// succeeded will send the output (if work produces them) and mark the work performed.
+// If the task has a future, the output is only given to the future.
+func (p *Pool[I, O]) succeeded(t task[I], res O) {
+	if future, ok := t.future.(*Future[O]); ok {
+		future.resolve(res, nil)
+	} else if p.discardOutcomes.Load() {
+		// The job has been abandoned by the shutdown.
+	} else if p.outputHandler != nil {
+		p.handleOutput(res)
+	} else if p.producesOutputs() {
+		// The connector will mark the work as performed once the output is
+		// forwarded, so waiting on this pool also waits for its outputs to
+		// reach the connected (parent) pools.
+		connected := p.IsConnected()
+		p.outputs <- res
+		if connected {
+			return
+		}
+	}
 	p.performedWork(true)
 }
 
-// performWorkRegular will perform regular work.
-func (p *Pool[I, O]) performWorkRegular(job I) {
-	defer p.performedWork(true)
-	p.outputs <- p.workRegular(job)
+// handleOutput will hand the output over to the output handler, one at
+// a time, unless the settings allow concurrent calls.
+func (p *Pool[_, O]) handleOutput(res O) {
+	if !p.settings.ConcurrentOutputs {
+		p.outputHandlerLock.Lock()
+		defer p.outputHandlerLock.Unlock()
+	}
+	p.outputHandler(res)
 }
 
-// performWorkWithErrors will perform regular work with errors.
-func (p *Pool[I, O]) performWorkWithErrors(job I) {
-	res, err := p.workRegularWithErrors(job)
-	if err != nil {
-		p.errors <- PoolError[I]{
-			Job:   job,
-			Error: err,
+// failed will report the error and mark the work performed. If the task
+// has a future, the error is only given to the future.
+func (p *Pool[I, O]) failed(t task[I], poolErr PoolError[I]) {
+	if future, ok := t.future.(*Future[O]); ok {
+		future.resolve(*new(O), poolErr.Error)
+	} else if !p.discardOutcomes.Load() {
+		p.reportError(poolErr)
+	}
+	p.performedWork(false)
+}
+
+// attemptBeforeDeadline will attempt the job, if the job has a deadline, it's
+// abandoned when the deadline passes, as work might not respect the context.
+func (p *Pool[I, O]) attemptBeforeDeadline(ctx context.Context, l *laborer[I, O], job I) (O, int, []error, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return p.attempt(ctx, l, job)
+	}
+
+	// Attempt the job separately, so the laborer can leave it behind.
+	type attempted struct {
+		res      O
+		attempts int
+		previous []error
+		err      error
+	}
+	done, returned := make(chan attempted, 1), make(chan Signal)
+	go func() {
+		defer close(returned)
+		res, attempts, previous, err := p.attempt(ctx, l, job)
+		done <- attempted{res, attempts, previous, err}
+	}()
+
+	deadline := ctx.Done()
+	for {
+		select {
+		case a := <-done:
+			// Work that respects its context fails right after the deadline.
+			if a.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				a.err = fmt.Errorf("%w: %w", ErrJobTimeout, a.err)
+			}
+			return a.res, a.attempts, a.previous, a.err
+		case <-deadline:
+			// If the context was cancelled, rather than timed out, keep
+			// waiting for the job as usual.
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				deadline = nil
+				continue
+			}
+			p.log.Warn("Abandoned a job after its deadline")
+			// The job might still be using the laborer's state.
+			if p.isWorkState() {
+				l.abandoned = returned
+			}
+			return *new(O), 0, nil, ErrJobTimeout
+		}
+	}
+}
+
+// attempt will call the work performer on the job until it succeeds or the
+// retry policy gives up on it, waiting between attempts as the policy says.
+// Returns the last attempt's results, the number of attempts and the errors
+// of the attempts before the last one.
+func (p *Pool[I, O]) attempt(ctx context.Context, l *laborer[I, O], job I) (res O, attempts int, previous []error, err error) {
+	retry := p.settings.Retry
+	for {
+		// Respect the rate limit before every attempt.
+		if err = p.throttle(ctx); err != nil {
+			return
+		}
+		attempts++
+		res, err = p.call(ctx, l, job)
+		if err == nil || !retry.allows(attempts, err) {
+			return
 		}
-		p.performedWork(false)
+		// Back off before the next attempt, unless the job's context is done.
+		timer := time.NewTimer(retry.delay(attempts))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		previous = append(previous, err)
+		p.jobsRetried.Add(1)
+	}
+}
+
+// call will run the laborer's work performer on the job, converting
+// a panic in work into an error wrapping `ErrJobPanicked`.
+func (p *Pool[I, O]) call(ctx context.Context, l *laborer[I, O], job I) (res O, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{
+				value: r,
+				stack: debug.Stack(),
+			}
+		}
+	}()
+	return l.work(ctx, job)
+}
+
+// reportError will hand the pool error over to the error handler, if set, or send
+// it to the errors channel, if work doesn't produce errors (it can still panic),
+// the error is logged.
+func (p *Pool[I, _]) reportError(poolErr PoolError[I]) {
+	if p.errorHandler != nil {
+		p.errorHandler(poolErr)
 		return
 	}
-	p.outputs <- res
-	p.performedWork(true)
+	if p.producesErrors() {
+		p.errors <- poolErr
+		return
+	}
+	p.log.Error("Work failed", "err", poolErr.Error)
+}
+
+// jobContext returns the context the task's work should run with, which is
+// cancelled when either the submission or the pool-level context is done, and
+// times out after the job timeout, unless the submission has its own deadline.
+func (p *Pool[I, _]) jobContext(t task[I]) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(t.ctx)
+	stop := func() bool { return false }
+	if t.ctx != p.ctx {
+		stop = context.AfterFunc(p.ctx, cancel)
+	}
+	cancelTimeout := context.CancelFunc(func() {})
+	if _, ok := t.ctx.Deadline(); p.settings.JobTimeout > 0 && (t.ctx == p.ctx || !ok) {
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.settings.JobTimeout)
+	}
+	return ctx, func() {
+		cancelTimeout()
+		stop()
+		cancel()
+	}
 }
 
 // performedWork will reduce the number of waiting jobs and increase
 // the number of completed jobs.
 func (p *Pool[_, _]) performedWork(success bool) {
-	p.jobsWaiting.Add(-1)
 	p.jobsCompleted.Add(1)
 	if success {
 		p.jobsSucceeded.Add(1)
 	}
 
-	// If we are concurrently waiting for jobs, send a signal
-	// if the number of waiting jobs is 0.
-	if p.jobsWaiting.Load() < 1 && p.currentlyWaitingForJobs.Load() {
-		p.noJobsCurrentlyWaitingSignal <- signal
+	// If no more jobs are waiting, let anyone waiting know.
+	if p.jobsWaiting.Add(-1) < 1 {
+		p.noJobsWaitingSignal.broadcast()
 	}
 }